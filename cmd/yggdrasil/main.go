@@ -16,6 +16,7 @@ import (
 	"strings"
 	"sync"
 	"syscall"
+	"time"
 
 	"golang.org/x/text/encoding/unicode"
 
@@ -32,6 +33,7 @@ import (
 	"github.com/yggdrasil-network/yggdrasil-go/src/ipv6rwc"
 
 	"github.com/yggdrasil-network/yggdrasil-go/src/core"
+	"github.com/yggdrasil-network/yggdrasil-go/src/metrics"
 	"github.com/yggdrasil-network/yggdrasil-go/src/multicast"
 	"github.com/yggdrasil-network/yggdrasil-go/src/tun"
 	"github.com/yggdrasil-network/yggdrasil-go/src/version"
@@ -42,6 +44,11 @@ type node struct {
 	tun       *tun.TunAdapter
 	multicast *multicast.Multicast
 	admin     *admin.AdminSocket
+	metrics   *metrics.Metrics
+	log       *log.Logger
+	mutex     sync.Mutex         // protects config
+	config    *config.NodeConfig // the configuration that is in effect
+	path      string             // where the configuration was read from, if a file
 }
 
 func readConfig(log *log.Logger, useconf bool, useconffile string, normaliseconf bool) *config.NodeConfig {
@@ -60,17 +67,28 @@ func readConfig(log *log.Logger, useconf bool, useconffile string, normaliseconf
 	if err != nil {
 		panic(err)
 	}
+	cfg, err := parseConfig(conf)
+	if err != nil {
+		panic(err)
+	}
+	return cfg
+}
+
+// parseConfig parses a configuration in HJSON or JSON format on top of the
+// default configuration.
+func parseConfig(conf []byte) (*config.NodeConfig, error) {
+	var err error
 	// If there's a byte order mark - which Windows 10 is now incredibly fond of
 	// throwing everywhere when it's converting things into UTF-16 for the hell
 	// of it - remove it and decode back down into UTF-8. This is necessary
 	// because hjson doesn't know what to do with UTF-16 and will panic
-	if bytes.Equal(conf[0:2], []byte{0xFF, 0xFE}) ||
-		bytes.Equal(conf[0:2], []byte{0xFE, 0xFF}) {
+	if bytes.HasPrefix(conf, []byte{0xFF, 0xFE}) ||
+		bytes.HasPrefix(conf, []byte{0xFE, 0xFF}) {
 		utf := unicode.UTF16(unicode.BigEndian, unicode.UseBOM)
 		decoder := utf.NewDecoder()
 		conf, err = decoder.Bytes(conf)
 		if err != nil {
-			panic(err)
+			return nil, err
 		}
 	}
 	// Generate a new configuration - this gives us a set of sane defaults -
@@ -80,22 +98,22 @@ func readConfig(log *log.Logger, useconf bool, useconffile string, normaliseconf
 	cfg := defaults.GenerateConfig()
 	var dat map[string]interface{}
 	if err := hjson.Unmarshal(conf, &dat); err != nil {
-		panic(err)
+		return nil, err
 	}
 	// Sanitise the config
 	confJson, err := json.Marshal(dat)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 	if err := json.Unmarshal(confJson, &cfg); err != nil {
-		panic(err)
+		return nil, err
 	}
 	// Overlay our newly mapped configuration onto the autoconf node config that
 	// we generated above.
 	if err = mapstructure.Decode(dat, &cfg); err != nil {
-		panic(err)
+		return nil, err
 	}
-	return cfg
+	return cfg, nil
 }
 
 // Generates a new configuration and returns it in HJSON format. This is used
@@ -287,7 +305,11 @@ func run(args yggArgs, ctx context.Context) {
 		return
 	}
 
-	n := &node{}
+	n := &node{
+		log:    logger,
+		config: cfg,
+		path:   args.useconffile,
+	}
 
 	// Setup the Yggdrasil node itself.
 	{
@@ -310,6 +332,20 @@ func run(args yggArgs, ctx context.Context) {
 				options = append(options, core.Peer{URI: peer, SourceInterface: intf})
 			}
 		}
+		options = append(options,
+			core.PeerRetryMinInterval(time.Duration(cfg.PeerRetryMinInterval)*time.Second),
+			core.PeerRetryMaxInterval(time.Duration(cfg.PeerRetryMaxInterval)*time.Second),
+			core.HeartbeatInterval(time.Duration(cfg.HeartbeatInterval)*time.Second),
+			core.HeartbeatTimeout(time.Duration(cfg.HeartbeatTimeout)*time.Second),
+			core.MaxUploadRate(cfg.MaxUploadRate),
+			core.MaxDownloadRate(cfg.MaxDownloadRate),
+			core.TLSCertificateSubject(cfg.TLSCertificateSubject),
+			core.MaxInboundPeers(cfg.MaxInboundPeers),
+			core.MaxInboundPeersPerSource(cfg.MaxInboundPeersPerSource),
+			core.InboundHandshakeRate(cfg.InboundHandshakeRate),
+			core.AutoBanThreshold(cfg.AutoBanThreshold),
+			core.AutoBanDuration(time.Duration(cfg.AutoBanDuration)*time.Second),
+		)
 		for _, allowed := range cfg.AllowedPublicKeys {
 			k, err := hex.DecodeString(allowed)
 			if err != nil {
@@ -317,6 +353,23 @@ func run(args yggArgs, ctx context.Context) {
 			}
 			options = append(options, core.AllowedPublicKey(k[:]))
 		}
+		for _, blocked := range cfg.BlockedPublicKeys {
+			k, err := hex.DecodeString(blocked)
+			if err != nil {
+				panic(err)
+			}
+			options = append(options, core.BlockedPublicKey(k[:]))
+		}
+		for _, blocked := range cfg.BlockedAddresses {
+			options = append(options, core.BlockedAddress(blocked))
+		}
+		lists, err := peerLists(cfg)
+		if err != nil {
+			panic(err)
+		}
+		for _, list := range lists {
+			options = append(options, list)
+		}
 		if n.core, err = core.New(sk[:], logger, options...); err != nil {
 			panic(err)
 		}
@@ -326,6 +379,17 @@ func run(args yggArgs, ctx context.Context) {
 	{
 		options := []admin.SetupOption{
 			admin.ListenAddress(cfg.AdminListen),
+			admin.HTTPListenAddress(cfg.AdminHTTPListen),
+			admin.TLSCertificate(cfg.AdminTLSCertificate),
+			admin.TLSKey(cfg.AdminTLSKey),
+			admin.TLSClientCA(cfg.AdminTLSClientCA),
+		}
+		for _, token := range cfg.AdminTokens {
+			options = append(options, admin.AuthToken{
+				Token:    token.Token,
+				Role:     token.Role,
+				Commands: token.Commands,
+			})
 		}
 		if n.admin, err = admin.New(n.core, logger, options...); err != nil {
 			panic(err)
@@ -337,15 +401,13 @@ func run(args yggArgs, ctx context.Context) {
 
 	// Setup the multicast module.
 	{
+		interfaces, err := multicastInterfaces(cfg)
+		if err != nil {
+			panic(err)
+		}
 		options := []multicast.SetupOption{}
-		for _, intf := range cfg.MulticastInterfaces {
-			options = append(options, multicast.MulticastInterface{
-				Regex:    regexp.MustCompile(intf.Regex),
-				Beacon:   intf.Beacon,
-				Listen:   intf.Listen,
-				Port:     intf.Port,
-				Priority: uint8(intf.Priority),
-			})
+		for _, intf := range interfaces {
+			options = append(options, intf)
 		}
 		if n.multicast, err = multicast.New(n.core, logger, options...); err != nil {
 			panic(err)
@@ -369,6 +431,26 @@ func run(args yggArgs, ctx context.Context) {
 		}
 	}
 
+	// Setup the metrics exporter.
+	{
+		options := []metrics.SetupOption{
+			metrics.ListenAddress(cfg.MetricsListen),
+			metrics.SessionMetrics(cfg.MetricsPerSession),
+		}
+		if n.metrics, err = metrics.New(n.core, logger, options...); err != nil {
+			panic(err)
+		}
+		if n.metrics != nil {
+			n.multicast.SetupMetrics(n.metrics)
+			n.tun.SetupMetrics(n.metrics)
+		}
+	}
+
+	// Setup the admin handlers that need the whole node.
+	if n.admin != nil {
+		n.setupAdminHandlers()
+	}
+
 	// Make some nice output that tells us what our IPv6 address and subnet are.
 	// This is just logged to stdout for the user.
 	address := n.core.Address()
@@ -378,16 +460,69 @@ func run(args yggArgs, ctx context.Context) {
 	logger.Infof("Your IPv6 address is %s", address.String())
 	logger.Infof("Your IPv6 subnet is %s", subnet.String())
 
-	// Block until we are told to shut down.
-	<-ctx.Done()
+	// Block until we are told to shut down, reloading the configuration
+	// whenever we are sent a SIGHUP.
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	for running := true; running; {
+		select {
+		case <-ctx.Done():
+			running = false
+		case <-hup:
+			if _, err := n.reloadConfig(); err != nil {
+				logger.Errorln("Failed to reload configuration:", err)
+			}
+		}
+	}
 
 	// Shut down the node.
 	_ = n.admin.Stop()
+	_ = n.metrics.Stop()
 	_ = n.multicast.Stop()
 	_ = n.tun.Stop()
 	n.core.Stop()
 }
 
+// multicastInterfaces converts the multicast interface configuration into the
+// form used by the multicast module.
+func multicastInterfaces(cfg *config.NodeConfig) ([]multicast.MulticastInterface, error) {
+	interfaces := make([]multicast.MulticastInterface, 0, len(cfg.MulticastInterfaces))
+	for _, intf := range cfg.MulticastInterfaces {
+		regex, err := regexp.Compile(intf.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid multicast interface regex %q: %w", intf.Regex, err)
+		}
+		interfaces = append(interfaces, multicast.MulticastInterface{
+			Regex:    regex,
+			Beacon:   intf.Beacon,
+			Listen:   intf.Listen,
+			Port:     intf.Port,
+			Priority: uint8(intf.Priority),
+		})
+	}
+	return interfaces, nil
+}
+
+// peerLists converts the PeerLists from the configuration into the form that
+// the core needs, keyed by URL.
+func peerLists(cfg *config.NodeConfig) (map[string]core.PeerList, error) {
+	lists := make(map[string]core.PeerList, len(cfg.PeerLists))
+	for _, list := range cfg.PeerLists {
+		key, err := hex.DecodeString(list.PublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid public key for peer list %q: %w", list.URL, err)
+		}
+		lists[list.URL] = core.PeerList{
+			URL:       list.URL,
+			PublicKey: key,
+			Refresh:   time.Duration(list.Refresh) * time.Second,
+			CacheFile: list.CacheFile,
+		}
+	}
+	return lists, nil
+}
+
 func main() {
 	args := getArgs()
 
@@ -0,0 +1,386 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/yggdrasil-network/yggdrasil-go/src/config"
+	"github.com/yggdrasil-network/yggdrasil-go/src/core"
+)
+
+// The configuration options that reloadConfig can apply to the running node.
+// Changes to any other option are only logged, as they need a restart.
+var reloadableOptions = map[string]struct{}{
+	"Peers":               {},
+	"InterfacePeers":      {},
+	"PeerLists":           {},
+	"Listen":              {},
+	"AllowedPublicKeys":   {},
+	"BlockedPublicKeys":   {},
+	"BlockedAddresses":    {},
+	"MulticastInterfaces": {},
+	"NodeInfo":            {},
+	"NodeInfoPrivacy":     {},
+}
+
+type ReloadConfigRequest struct{}
+
+type ReloadConfigResponse struct {
+	Applied         []string `json:"applied"`
+	RestartRequired []string `json:"restart_required"`
+}
+
+func (n *node) setupAdminHandlers() {
+	_ = n.admin.AddHandler(
+		"reloadConfig", "Re-read the configuration file and apply any changes that don't need a restart", []string{},
+		func(in json.RawMessage) (interface{}, error) {
+			return n.reloadConfig()
+		},
+	)
+}
+
+// reloadConfig reads the configuration file again and applies the differences
+// from the configuration that is in effect. The private key can't be changed
+// without a restart, so the whole reload is refused if it has changed. Other
+// options that need a restart are left as they are, with a warning.
+func (n *node) reloadConfig() (*ReloadConfigResponse, error) {
+	if n.path == "" {
+		return nil, errors.New("configuration can only be reloaded if it was read with -useconffile")
+	}
+	conf, err := os.ReadFile(n.path)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := parseConfig(conf)
+	if err != nil {
+		return nil, err
+	}
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if cfg.PrivateKey != n.config.PrivateKey {
+		return nil, errors.New("PrivateKey has changed, restart to use the new key")
+	}
+	cfg.PublicKey = n.config.PublicKey
+	res := &ReloadConfigResponse{
+		Applied:         []string{},
+		RestartRequired: []string{},
+	}
+
+	// Leave the options that need a restart as they are, so that the running
+	// configuration reflects what is actually in effect.
+	running, reloaded := reflect.ValueOf(n.config).Elem(), reflect.ValueOf(cfg).Elem()
+	for i := 0; i < running.NumField(); i++ {
+		name := running.Type().Field(i).Name
+		if reflect.DeepEqual(running.Field(i).Interface(), reloaded.Field(i).Interface()) {
+			continue
+		}
+		if _, ok := reloadableOptions[name]; ok {
+			res.Applied = append(res.Applied, name)
+			continue
+		}
+		res.RestartRequired = append(res.RestartRequired, name)
+		reloaded.Field(i).Set(running.Field(i))
+	}
+	for _, name := range res.RestartRequired {
+		n.log.Warnf("Configuration option %s has changed, but will not take effect until restart", name)
+	}
+	if len(res.Applied) == 0 {
+		n.log.Infoln("Configuration reloaded, no changes to apply")
+		n.publishReload(res, nil)
+		return res, nil
+	}
+
+	// Check everything before changing anything, so that a mistake in the
+	// file doesn't leave the node half reconfigured.
+	allowed, err := decodeKeys(cfg.AllowedPublicKeys)
+	if err != nil {
+		return nil, fmt.Errorf("AllowedPublicKeys: %w", err)
+	}
+	blocked, err := decodeKeys(cfg.BlockedPublicKeys)
+	if err != nil {
+		return nil, fmt.Errorf("BlockedPublicKeys: %w", err)
+	}
+	interfaces, err := multicastInterfaces(cfg)
+	if err != nil {
+		return nil, err
+	}
+	lists, err := peerLists(cfg)
+	if err != nil {
+		return nil, err
+	}
+	oldLists, _ := peerLists(n.config)
+	oldAllowed, _ := decodeKeys(n.config.AllowedPublicKeys)
+	oldBlocked, _ := decodeKeys(n.config.BlockedPublicKeys)
+
+	// If a change can't be made, then that item is put back to its old value
+	// in the new configuration, so that the running configuration reflects
+	// what is actually in effect and the next reload tries it again.
+	var errs []error
+	failed := func(err error, revert func()) bool {
+		if err == nil {
+			return false
+		}
+		errs = append(errs, err)
+		revert()
+		return true
+	}
+	oldPeers, newPeers := configuredPeers(n.config), configuredPeers(cfg)
+	for peer := range oldPeers {
+		if _, ok := newPeers[peer]; !ok {
+			failed(n.core.RemovePeer(peer.URI, peer.SourceInterface), func() {
+				addConfiguredPeer(cfg, peer)
+			})
+		}
+	}
+	for peer := range newPeers {
+		if _, ok := oldPeers[peer]; !ok {
+			failed(n.core.AddPeer(peer.URI, peer.SourceInterface), func() {
+				removeConfiguredPeer(cfg, peer)
+			})
+		}
+	}
+	for uri, list := range oldLists {
+		if !reflect.DeepEqual(list, lists[uri]) {
+			if failed(n.core.RemovePeerList(uri), func() {
+				setPeerList(cfg, uri, findPeerList(n.config, uri))
+			}) {
+				delete(lists, uri)
+			}
+		}
+	}
+	for uri, list := range lists {
+		if !reflect.DeepEqual(list, oldLists[uri]) {
+			failed(n.core.AddPeerList(list), func() {
+				setPeerList(cfg, uri, nil)
+			})
+		}
+	}
+	removed, added := diffStrings(n.config.Listen, cfg.Listen)
+	for _, uri := range removed {
+		failed(n.core.RemoveListener(uri, ""), func() {
+			cfg.Listen = append(cfg.Listen, uri)
+		})
+	}
+	for _, uri := range added {
+		failed(n.core.AddListener(uri, ""), func() {
+			cfg.Listen = withoutString(cfg.Listen, uri)
+		})
+	}
+	removed, added = diffStrings(keys(oldAllowed), keys(allowed))
+	for _, key := range removed {
+		failed(n.core.RemoveAllowedPublicKey(oldAllowed[key]), func() {
+			cfg.AllowedPublicKeys = append(cfg.AllowedPublicKeys, key)
+		})
+	}
+	for _, key := range added {
+		failed(n.core.AddAllowedPublicKey(allowed[key]), func() {
+			cfg.AllowedPublicKeys = withoutKey(cfg.AllowedPublicKeys, key)
+		})
+	}
+	removed, added = diffStrings(keys(oldBlocked), keys(blocked))
+	for _, key := range removed {
+		failed(n.core.UnblockPublicKey(oldBlocked[key]), func() {
+			cfg.BlockedPublicKeys = append(cfg.BlockedPublicKeys, key)
+		})
+	}
+	for _, key := range added {
+		failed(n.core.BlockPublicKey(blocked[key]), func() {
+			cfg.BlockedPublicKeys = withoutKey(cfg.BlockedPublicKeys, key)
+		})
+	}
+	removed, added = diffStrings(n.config.BlockedAddresses, cfg.BlockedAddresses)
+	for _, addr := range removed {
+		failed(n.core.UnblockAddress(addr), func() {
+			cfg.BlockedAddresses = append(cfg.BlockedAddresses, addr)
+		})
+	}
+	for _, addr := range added {
+		failed(n.core.BlockAddress(addr), func() {
+			cfg.BlockedAddresses = withoutString(cfg.BlockedAddresses, addr)
+		})
+	}
+	if !reflect.DeepEqual(n.config.MulticastInterfaces, cfg.MulticastInterfaces) && n.multicast != nil {
+		failed(n.multicast.SetInterfaces(interfaces...), func() {
+			cfg.MulticastInterfaces = n.config.MulticastInterfaces
+		})
+	}
+	if !reflect.DeepEqual(n.config.NodeInfo, cfg.NodeInfo) || n.config.NodeInfoPrivacy != cfg.NodeInfoPrivacy {
+		failed(n.core.SetNodeInfo(cfg.NodeInfo, core.NodeInfoPrivacy(cfg.NodeInfoPrivacy)), func() {
+			cfg.NodeInfo, cfg.NodeInfoPrivacy = n.config.NodeInfo, n.config.NodeInfoPrivacy
+		})
+	}
+
+	// Options where every change failed haven't been applied after all.
+	applied := res.Applied[:0]
+	for _, name := range res.Applied {
+		if !reflect.DeepEqual(running.FieldByName(name).Interface(), reloaded.FieldByName(name).Interface()) {
+			applied = append(applied, name)
+		}
+	}
+	res.Applied = applied
+	n.config = cfg
+
+	if err = errors.Join(errs...); err != nil {
+		n.log.Warnf("Configuration reloaded with errors, changed %v: %s", res.Applied, err)
+		n.publishReload(res, err)
+		return res, err
+	}
+	n.log.Infof("Configuration reloaded, changed %v", res.Applied)
+	n.publishReload(res, nil)
+	return res, nil
+}
+
+// publishReload tells event subscribers which options were reloaded.
+func (n *node) publishReload(res *ReloadConfigResponse, err error) {
+	fields := map[string]string{
+		"applied":          strings.Join(res.Applied, ","),
+		"restart_required": strings.Join(res.RestartRequired, ","),
+	}
+	if err != nil {
+		fields["error"] = err.Error()
+	}
+	n.core.PublishEvent(core.Event{
+		Type:   core.EventConfigReloaded,
+		Fields: fields,
+	})
+}
+
+// configuredPeers returns the set of peers from Peers and InterfacePeers.
+func configuredPeers(cfg *config.NodeConfig) map[core.Peer]struct{} {
+	peers := make(map[core.Peer]struct{})
+	for _, peer := range cfg.Peers {
+		peers[core.Peer{URI: peer}] = struct{}{}
+	}
+	for intf, intfPeers := range cfg.InterfacePeers {
+		for _, peer := range intfPeers {
+			peers[core.Peer{URI: peer, SourceInterface: intf}] = struct{}{}
+		}
+	}
+	return peers
+}
+
+// addConfiguredPeer adds a peer to Peers or InterfacePeers.
+func addConfiguredPeer(cfg *config.NodeConfig, peer core.Peer) {
+	if peer.SourceInterface == "" {
+		cfg.Peers = append(cfg.Peers, peer.URI)
+		return
+	}
+	if cfg.InterfacePeers == nil {
+		cfg.InterfacePeers = make(map[string][]string)
+	}
+	cfg.InterfacePeers[peer.SourceInterface] = append(cfg.InterfacePeers[peer.SourceInterface], peer.URI)
+}
+
+// removeConfiguredPeer removes a peer from Peers or InterfacePeers.
+func removeConfiguredPeer(cfg *config.NodeConfig, peer core.Peer) {
+	if peer.SourceInterface == "" {
+		cfg.Peers = withoutString(cfg.Peers, peer.URI)
+		return
+	}
+	if peers := withoutString(cfg.InterfacePeers[peer.SourceInterface], peer.URI); len(peers) > 0 {
+		cfg.InterfacePeers[peer.SourceInterface] = peers
+	} else {
+		delete(cfg.InterfacePeers, peer.SourceInterface)
+	}
+}
+
+// findPeerList returns the entry in PeerLists with the given URL, or nil.
+func findPeerList(cfg *config.NodeConfig, uri string) *config.PeerListConfig {
+	for i := range cfg.PeerLists {
+		if cfg.PeerLists[i].URL == uri {
+			return &cfg.PeerLists[i]
+		}
+	}
+	return nil
+}
+
+// setPeerList replaces the entry in PeerLists with the given URL, or removes
+// it if list is nil.
+func setPeerList(cfg *config.NodeConfig, uri string, list *config.PeerListConfig) {
+	lists := make([]config.PeerListConfig, 0, len(cfg.PeerLists)+1)
+	for _, l := range cfg.PeerLists {
+		if l.URL != uri {
+			lists = append(lists, l)
+		}
+	}
+	if list != nil {
+		lists = append(lists, *list)
+	}
+	cfg.PeerLists = lists
+}
+
+// decodeKeys decodes a list of hex-encoded public keys, keyed by their
+// canonical hex encoding.
+func decodeKeys(list []string) (map[string][]byte, error) {
+	decoded := make(map[string][]byte, len(list))
+	for _, k := range list {
+		key, err := hex.DecodeString(k)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q: %w", k, err)
+		}
+		decoded[hex.EncodeToString(key)] = key
+	}
+	return decoded, nil
+}
+
+func keys(m map[string][]byte) []string {
+	list := make([]string, 0, len(m))
+	for k := range m {
+		list = append(list, k)
+	}
+	return list
+}
+
+// withoutString returns a copy of the list with every copy of s removed.
+func withoutString(list []string, s string) []string {
+	without := make([]string, 0, len(list))
+	for _, l := range list {
+		if l != s {
+			without = append(without, l)
+		}
+	}
+	return without
+}
+
+// withoutKey returns a copy of a list of hex-encoded public keys with every
+// encoding of the given key removed.
+func withoutKey(list []string, key string) []string {
+	without := make([]string, 0, len(list))
+	for _, l := range list {
+		if k, err := hex.DecodeString(l); err != nil || hex.EncodeToString(k) != key {
+			without = append(without, l)
+		}
+	}
+	return without
+}
+
+// diffStrings returns the strings that are only in the old list and those that
+// are only in the new list. Each string is only returned once, even if it is
+// repeated in the list.
+func diffStrings(before, after []string) (removed, added []string) {
+	inBefore := make(map[string]struct{}, len(before))
+	for _, s := range before {
+		inBefore[s] = struct{}{}
+	}
+	inAfter := make(map[string]struct{}, len(after))
+	for _, s := range after {
+		if _, ok := inAfter[s]; ok {
+			continue
+		}
+		inAfter[s] = struct{}{}
+		if _, ok := inBefore[s]; !ok {
+			added = append(added, s)
+		}
+	}
+	for s := range inBefore {
+		if _, ok := inAfter[s]; !ok {
+			removed = append(removed, s)
+		}
+	}
+	return
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gologme/log"
+
+	"github.com/yggdrasil-network/yggdrasil-go/src/config"
+	"github.com/yggdrasil-network/yggdrasil-go/src/core"
+	"github.com/yggdrasil-network/yggdrasil-go/src/defaults"
+)
+
+func TestDiffStrings(t *testing.T) {
+	for _, test := range []struct {
+		before, after, removed, added []string
+	}{
+		{nil, nil, nil, nil},
+		{[]string{"a", "b"}, []string{"a", "b"}, nil, nil},
+		{[]string{"a", "b"}, []string{"b", "a"}, nil, nil},
+		{nil, []string{"a"}, nil, []string{"a"}},
+		{[]string{"a"}, nil, []string{"a"}, nil},
+		{[]string{"a", "b", "b"}, []string{"b", "c", "c"}, []string{"a"}, []string{"c"}},
+	} {
+		removed, added := diffStrings(test.before, test.after)
+		sort.Strings(removed)
+		if !reflect.DeepEqual(removed, test.removed) || !reflect.DeepEqual(added, test.added) {
+			t.Errorf("diffStrings(%v, %v) = %v, %v, expected %v, %v",
+				test.before, test.after, removed, added, test.removed, test.added)
+		}
+	}
+}
+
+// testReloadNode starts a node with the given configuration, which is written
+// to a file so that it can be reloaded.
+func testReloadNode(t *testing.T, cfg *config.NodeConfig) *node {
+	t.Helper()
+	sk, err := hex.DecodeString(cfg.PrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := log.New(os.Stderr, "", log.Flags())
+	c, err := core.New(sk, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(c.Stop)
+	n := &node{
+		core:   c,
+		log:    logger,
+		config: cfg,
+		path:   filepath.Join(t.TempDir(), "yggdrasil.conf"),
+	}
+	writeTestConfig(t, n.path, cfg)
+	return n
+}
+
+func writeTestConfig(t *testing.T, path string, cfg *config.NodeConfig) {
+	t.Helper()
+	bs, err := json.Marshal(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(path, bs, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestReloadConfig_PartialFailure reloads a configuration where some changes
+// can't be applied, and checks that the others still are, that the options
+// that need a restart are left alone, and that the changes that failed are
+// tried again on the next reload.
+func TestReloadConfig_PartialFailure(t *testing.T) {
+	running := defaults.GenerateConfig()
+	n := testReloadNode(t, running)
+
+	// Hold a port so that listening on it fails until it is released.
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+	busyURI := "tcp://" + busy.Addr().String()
+
+	changed := *running
+	changed.Listen = []string{"tcp://127.0.0.1:0", busyURI}
+	changed.Peers = []string{"unknown://127.0.0.1:1"}
+	changed.BlockedAddresses = []string{"192.0.2.1"}
+	changed.IfName = "changed"
+	writeTestConfig(t, n.path, &changed)
+
+	res, err := n.reloadConfig()
+	if err == nil {
+		t.Fatal("expected the reload to fail for the busy listener and the unknown peer scheme")
+	}
+	sort.Strings(res.Applied)
+	if expected := []string{"BlockedAddresses", "Listen"}; !reflect.DeepEqual(res.Applied, expected) {
+		t.Fatalf("applied %v, expected %v", res.Applied, expected)
+	}
+	if expected := []string{"IfName"}; !reflect.DeepEqual(res.RestartRequired, expected) {
+		t.Fatalf("restart required for %v, expected %v", res.RestartRequired, expected)
+	}
+
+	// The changes that could be made were made.
+	if listeners := n.core.GetListeners(); len(listeners) != 1 {
+		t.Fatalf("expected one listener to be started, got %d", len(listeners))
+	}
+	if blocked := n.core.GetBlocked().Addresses; len(blocked) != 1 {
+		t.Fatalf("expected one blocked address, got %v", blocked)
+	}
+	// The running configuration has the changes that were made, but not the
+	// ones that failed or that need a restart.
+	if !reflect.DeepEqual(n.config.BlockedAddresses, changed.BlockedAddresses) {
+		t.Fatalf("running configuration has blocked addresses %v", n.config.BlockedAddresses)
+	}
+	if expected := []string{"tcp://127.0.0.1:0"}; !reflect.DeepEqual(n.config.Listen, expected) {
+		t.Fatalf("running configuration has listeners %v, expected %v", n.config.Listen, expected)
+	}
+	if len(n.config.Peers) != 0 {
+		t.Fatalf("running configuration has peers %v that failed to be added", n.config.Peers)
+	}
+	if n.config.IfName == "changed" {
+		t.Fatal("running configuration has an option that needs a restart")
+	}
+
+	// Once the port is free, reloading the same file starts the listener that
+	// failed, and tries the peer again.
+	_ = busy.Close()
+	res, err = n.reloadConfig()
+	if err == nil {
+		t.Fatal("expected the reload to fail again for the unknown peer scheme")
+	}
+	if expected := []string{"Listen"}; !reflect.DeepEqual(res.Applied, expected) {
+		t.Fatalf("applied %v, expected %v", res.Applied, expected)
+	}
+	if listeners := n.core.GetListeners(); len(listeners) != 2 {
+		t.Fatalf("expected the failed listener to be started, got %d listeners", len(listeners))
+	}
+	if !reflect.DeepEqual(n.config.Listen, changed.Listen) {
+		t.Fatalf("running configuration has listeners %v, expected %v", n.config.Listen, changed.Listen)
+	}
+	if !strings.Contains(err.Error(), "unknown") {
+		t.Fatalf("the failed peer was not tried again: %v", err)
+	}
+}
+
+func TestReloadConfig_PrivateKey(t *testing.T) {
+	running := defaults.GenerateConfig()
+	n := testReloadNode(t, running)
+
+	changed := defaults.GenerateConfig()
+	changed.Listen = []string{"tcp://127.0.0.1:0"}
+	writeTestConfig(t, n.path, changed)
+	if _, err := n.reloadConfig(); err == nil {
+		t.Fatal("reload with a new private key did not fail")
+	}
+	if listeners := n.core.GetListeners(); len(listeners) != 0 {
+		t.Fatal("reload with a new private key changed other options")
+	}
+}
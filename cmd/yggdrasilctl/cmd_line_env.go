@@ -2,6 +2,8 @@ package main
 
 import (
 	"bytes"
+	"crypto/tls"
+	"crypto/x509"
 	"flag"
 	"fmt"
 	"log"
@@ -13,9 +15,16 @@ import (
 	"github.com/yggdrasil-network/yggdrasil-go/src/defaults"
 )
 
+// The environment variable that the admin token is read from if -token isn't
+// given, which keeps it out of the process list.
+const tokenEnvVar = "YGGDRASIL_ADMIN_TOKEN"
+
 type CmdLineEnv struct {
 	args             []string
 	endpoint, server string
+	token            string
+	tlsCA            string
+	tlsCert, tlsKey  string
 	injson, ver      bool
 }
 
@@ -42,11 +51,20 @@ func (cmdLineEnv *CmdLineEnv) parseFlagsAndArgs() {
 		fmt.Println("  - ", os.Args[0], "setTunTap name=auto mtu=1500 tap_mode=false")
 		fmt.Println("  - ", os.Args[0], "-endpoint=tcp://localhost:9001 getDHT")
 		fmt.Println("  - ", os.Args[0], "-endpoint=unix:///var/run/ygg.sock getDHT")
+		fmt.Println("  - ", os.Args[0], "-endpoint=tcp://[::1]:9001 -token=secret getPeers")
+		fmt.Println("  - ", os.Args[0], "watch types=peer_connected,peer_disconnected")
+		fmt.Println("  - ", os.Args[0], "-endpoint=tls://node.example.com:9001 -tlsca=ca.pem -tlscert=client.pem -tlskey=client.key getPeers")
+		fmt.Println()
+		fmt.Println("The token can also be given in the", tokenEnvVar, "environment variable.")
 	}
 
 	server := flag.String("endpoint", cmdLineEnv.endpoint, "Admin socket endpoint")
 	injson := flag.Bool("json", false, "Output in JSON format (as opposed to pretty-print)")
 	ver := flag.Bool("version", false, "Prints the version of this build")
+	tlsCA := flag.String("tlsca", "", "PEM file with the CA to verify a tls:// endpoint with, instead of the system CAs")
+	tlsCert := flag.String("tlscert", "", "PEM file with a client certificate for a tls:// endpoint")
+	tlsKey := flag.String("tlskey", "", "PEM file with the private key for -tlscert")
+	token := flag.String("token", os.Getenv(tokenEnvVar), "Token to authenticate to the admin socket with, if it requires one")
 
 	flag.Parse()
 
@@ -54,6 +72,10 @@ func (cmdLineEnv *CmdLineEnv) parseFlagsAndArgs() {
 	cmdLineEnv.server = *server
 	cmdLineEnv.injson = *injson
 	cmdLineEnv.ver = *ver
+	cmdLineEnv.token = *token
+	cmdLineEnv.tlsCA = *tlsCA
+	cmdLineEnv.tlsCert = *tlsCert
+	cmdLineEnv.tlsKey = *tlsKey
 }
 
 func (cmdLineEnv *CmdLineEnv) setEndpoint(logger *log.Logger) {
@@ -89,3 +111,31 @@ func (cmdLineEnv *CmdLineEnv) setEndpoint(logger *log.Logger) {
 		logger.Println("Using endpoint", cmdLineEnv.endpoint, "from command line")
 	}
 }
+
+// tlsConfig returns the TLS configuration for a tls:// endpoint, which checks
+// the server against the CA from -tlsca, or the system CAs if it isn't set,
+// and presents the client certificate from -tlscert if there is one.
+func (cmdLineEnv *CmdLineEnv) tlsConfig(serverName string) (*tls.Config, error) {
+	config := &tls.Config{
+		ServerName: serverName,
+		MinVersion: tls.VersionTLS12,
+	}
+	if cmdLineEnv.tlsCA != "" {
+		pem, err := os.ReadFile(cmdLineEnv.tlsCA)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", cmdLineEnv.tlsCA)
+		}
+	}
+	if cmdLineEnv.tlsCert != "" || cmdLineEnv.tlsKey != "" {
+		cert, err := tls.LoadX509KeyPair(cmdLineEnv.tlsCert, cmdLineEnv.tlsKey)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
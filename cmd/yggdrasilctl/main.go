@@ -2,14 +2,17 @@ package main
 
 import (
 	"bytes"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"net"
 	"net/url"
 	"os"
+	"sort"
 	"strings"
 	"time"
 
@@ -66,6 +69,12 @@ func run() int {
 		case "tcp":
 			logger.Println("Connecting to TCP socket", u.Host)
 			conn, err = net.Dial("tcp", u.Host)
+		case "tls":
+			logger.Println("Connecting to TLS socket", u.Host)
+			var config *tls.Config
+			if config, err = cmdLineEnv.tlsConfig(u.Hostname()); err == nil {
+				conn, err = tls.Dial("tcp", u.Host, config)
+			}
 		default:
 			logger.Println("Unknown protocol or malformed address - check your endpoint")
 			err = errors.New("protocol not supported")
@@ -83,7 +92,9 @@ func run() int {
 
 	decoder := json.NewDecoder(conn)
 	encoder := json.NewEncoder(conn)
-	send := &admin.AdminSocketRequest{}
+	send := &admin.AdminSocketRequest{
+		Auth: cmdLineEnv.token,
+	}
 	recv := &admin.AdminSocketResponse{}
 	args := map[string]string{}
 	for c, a := range cmdLineEnv.args {
@@ -92,6 +103,9 @@ func run() int {
 				logger.Printf("Ignoring flag %s as it should be specified before other parameters\n", a)
 				continue
 			}
+			if strings.EqualFold(a, "watch") {
+				a = "subscribe"
+			}
 			logger.Printf("Sending request: %v\n", a)
 			send.Name = a
 			continue
@@ -122,6 +136,9 @@ func run() int {
 		}
 		return 1
 	}
+	if strings.EqualFold(send.Name, "subscribe") {
+		return watch(decoder, cmdLineEnv.injson)
+	}
 	if cmdLineEnv.injson {
 		if json, err := json.MarshalIndent(recv.Response, "", "  "); err == nil {
 			fmt.Println(string(json))
@@ -174,13 +191,18 @@ func run() int {
 		if err := json.Unmarshal(recv.Response, &resp); err != nil {
 			panic(err)
 		}
-		table.SetHeader([]string{"Port", "Public Key", "IP Address", "Uptime", "RX", "TX", "Pr", "URI"})
+		table.SetHeader([]string{"Port", "Public Key", "IP Address", "Uptime", "RTT", "RX", "TX", "Pr", "URI"})
 		for _, peer := range resp.Peers {
+			rtt := "-"
+			if peer.RTT > 0 {
+				rtt = time.Duration(peer.RTT * float64(time.Second)).Round(time.Microsecond * 100).String()
+			}
 			table.Append([]string{
 				fmt.Sprintf("%d", peer.Port),
 				peer.PublicKey,
 				peer.IPAddress,
 				(time.Duration(peer.Uptime) * time.Second).String(),
+				rtt,
 				peer.RXBytes.String(),
 				peer.TXBytes.String(),
 				fmt.Sprintf("%d", peer.Priority),
@@ -189,6 +211,79 @@ func run() int {
 		}
 		table.Render()
 
+	case "getconfiguredpeers":
+		var resp admin.GetConfiguredPeersResponse
+		if err := json.Unmarshal(recv.Response, &resp); err != nil {
+			panic(err)
+		}
+		table.SetHeader([]string{"URI", "Interface", "Connected", "Failures", "Next Retry", "Source"})
+		for _, peer := range resp.Peers {
+			next := "-"
+			if peer.NextRetry > 0 {
+				next = time.Duration(peer.NextRetry * float64(time.Second)).Round(time.Second).String()
+			}
+			table.Append([]string{
+				peer.URI,
+				peer.Interface,
+				fmt.Sprintf("%t", peer.Connected),
+				fmt.Sprintf("%d", peer.Failures),
+				next,
+				peer.Source,
+			})
+		}
+		table.Render()
+
+	case "getlisteners":
+		var resp admin.GetListenersResponse
+		if err := json.Unmarshal(recv.Response, &resp); err != nil {
+			panic(err)
+		}
+		table.SetHeader([]string{"URI", "Interface", "Address", "Peers"})
+		for _, l := range resp.Listeners {
+			table.Append([]string{
+				l.URI,
+				l.Interface,
+				l.Address,
+				fmt.Sprintf("%d", l.Peers),
+			})
+		}
+		table.Render()
+
+	case "getallowedkeys":
+		var resp admin.GetAllowedKeysResponse
+		if err := json.Unmarshal(recv.Response, &resp); err != nil {
+			panic(err)
+		}
+		if len(resp.PublicKeys) == 0 {
+			fmt.Println("All public keys are allowed")
+			return 0
+		}
+		table.SetHeader([]string{"Allowed Public Key"})
+		for _, key := range resp.PublicKeys {
+			table.Append([]string{key})
+		}
+		table.Render()
+
+	case "getblocked":
+		var resp admin.GetBlockedResponse
+		if err := json.Unmarshal(recv.Response, &resp); err != nil {
+			panic(err)
+		}
+		table.SetHeader([]string{"Blocked", "Remaining"})
+		for _, key := range resp.PublicKeys {
+			table.Append([]string{key, "-"})
+		}
+		for _, addr := range resp.Addresses {
+			table.Append([]string{addr, "-"})
+		}
+		for _, ban := range resp.Bans {
+			table.Append([]string{
+				ban.Source,
+				time.Duration(ban.Remaining * float64(time.Second)).Round(time.Second).String(),
+			})
+		}
+		table.Render()
+
 	case "getdht":
 		var resp admin.GetDHTResponse
 		if err := json.Unmarshal(recv.Response, &resp); err != nil {
@@ -278,3 +373,42 @@ func run() int {
 
 	return 0
 }
+
+// watch prints events from a subscription, one per line, until the node closes
+// the connection.
+func watch(decoder *json.Decoder, injson bool) int {
+	for {
+		var recv admin.AdminSocketResponse
+		if err := decoder.Decode(&recv); err != nil {
+			if errors.Is(err, io.EOF) {
+				return 0
+			}
+			fmt.Println("Admin socket closed:", err)
+			return 1
+		}
+		if recv.Status != "event" {
+			continue
+		}
+		if injson {
+			fmt.Println(string(recv.Response))
+			continue
+		}
+		var event admin.EventEntry
+		if err := json.Unmarshal(recv.Response, &event); err != nil {
+			panic(err)
+		}
+		line := []string{event.Time.Local().Format(time.RFC3339), event.Type}
+		if event.IPAddress != "" {
+			line = append(line, event.IPAddress)
+		}
+		names := make([]string, 0, len(event.Fields))
+		for name := range event.Fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			line = append(line, fmt.Sprintf("%s=%q", name, event.Fields[name]))
+		}
+		fmt.Println(strings.Join(line, " "))
+	}
+}
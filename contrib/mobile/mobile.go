@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net"
 	"regexp"
+	"time"
 
 	"github.com/gologme/log"
 
@@ -64,6 +65,20 @@ func (m *Yggdrasil) StartJSON(configjson []byte) error {
 				options = append(options, core.Peer{URI: peer, SourceInterface: intf})
 			}
 		}
+		options = append(options,
+			core.PeerRetryMinInterval(time.Duration(m.config.PeerRetryMinInterval)*time.Second),
+			core.PeerRetryMaxInterval(time.Duration(m.config.PeerRetryMaxInterval)*time.Second),
+			core.HeartbeatInterval(time.Duration(m.config.HeartbeatInterval)*time.Second),
+			core.HeartbeatTimeout(time.Duration(m.config.HeartbeatTimeout)*time.Second),
+			core.MaxUploadRate(m.config.MaxUploadRate),
+			core.MaxDownloadRate(m.config.MaxDownloadRate),
+			core.TLSCertificateSubject(m.config.TLSCertificateSubject),
+			core.MaxInboundPeers(m.config.MaxInboundPeers),
+			core.MaxInboundPeersPerSource(m.config.MaxInboundPeersPerSource),
+			core.InboundHandshakeRate(m.config.InboundHandshakeRate),
+			core.AutoBanThreshold(m.config.AutoBanThreshold),
+			core.AutoBanDuration(time.Duration(m.config.AutoBanDuration)*time.Second),
+		)
 		for _, allowed := range m.config.AllowedPublicKeys {
 			k, err := hex.DecodeString(allowed)
 			if err != nil {
@@ -71,6 +86,28 @@ func (m *Yggdrasil) StartJSON(configjson []byte) error {
 			}
 			options = append(options, core.AllowedPublicKey(k[:]))
 		}
+		for _, blocked := range m.config.BlockedPublicKeys {
+			k, err := hex.DecodeString(blocked)
+			if err != nil {
+				panic(err)
+			}
+			options = append(options, core.BlockedPublicKey(k[:]))
+		}
+		for _, blocked := range m.config.BlockedAddresses {
+			options = append(options, core.BlockedAddress(blocked))
+		}
+		for _, list := range m.config.PeerLists {
+			k, err := hex.DecodeString(list.PublicKey)
+			if err != nil {
+				panic(err)
+			}
+			options = append(options, core.PeerList{
+				URL:       list.URL,
+				PublicKey: k,
+				Refresh:   time.Duration(list.Refresh) * time.Second,
+				CacheFile: list.CacheFile,
+			})
+		}
 		m.core, err = core.New(sk[:], logger, options...)
 		if err != nil {
 			panic(err)
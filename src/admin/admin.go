@@ -1,10 +1,13 @@
 package admin
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net"
+	"net/http"
 	"net/url"
 	"os"
 	"sort"
@@ -15,16 +18,21 @@ import (
 	"github.com/yggdrasil-network/yggdrasil-go/src/core"
 )
 
-// TODO: Add authentication
-
 type AdminSocket struct {
 	core     *core.Core
 	log      core.Logger
 	listener net.Listener
+	http     *http.Server // the HTTP gateway, if it is enabled
 	handlers map[string]handler
 	done     chan struct{}
 	config   struct {
-		listenaddr ListenAddress
+		listenaddr     ListenAddress
+		httpListenAddr string
+		tlsCertificate string
+		tlsKey         string
+		tlsClientCA    string
+		authRequired   bool                    // set if any tokens are configured
+		tokens         map[[32]byte]*authToken // by SHA-256 of the token
 	}
 }
 
@@ -32,6 +40,7 @@ type AdminSocketRequest struct {
 	Name      string          `json:"request"`
 	Arguments json.RawMessage `json:"arguments,omitempty"`
 	KeepAlive bool            `json:"keepalive,omitempty"`
+	Auth      string          `json:"auth,omitempty"` // token, if authentication is required
 }
 
 type AdminSocketResponse struct {
@@ -77,10 +86,12 @@ func New(c *core.Core, log core.Logger, opts ...SetupOption) (*AdminSocket, erro
 		log:      log,
 		handlers: make(map[string]handler),
 	}
+	a.config.tokens = make(map[[32]byte]*authToken)
 	for _, opt := range opts {
 		a._applyOption(opt)
 	}
-	if a.config.listenaddr == "none" || a.config.listenaddr == "" {
+	socket := a.config.listenaddr != "none" && a.config.listenaddr != ""
+	if !socket && a.config.httpListenAddr == "" {
 		return nil, nil
 	}
 	_ = a.AddHandler("list", "List available commands", []string{}, func(_ json.RawMessage) (interface{}, error) {
@@ -98,7 +109,14 @@ func New(c *core.Core, log core.Logger, opts ...SetupOption) (*AdminSocket, erro
 		return res, nil
 	})
 	a.done = make(chan struct{})
-	go a.listen()
+	if socket {
+		go a.listen()
+	}
+	if a.config.httpListenAddr != "" {
+		if err := a.listenHTTP(); err != nil {
+			return nil, err
+		}
+	}
 	return a, a.core.SetAdmin(a)
 }
 
@@ -131,6 +149,20 @@ func (a *AdminSocket) SetupAdminHandlers() {
 			return res, nil
 		},
 	)
+	_ = a.AddHandler(
+		"getConfiguredPeers", "Show peers from the peer list and their reconnection state", []string{},
+		func(in json.RawMessage) (interface{}, error) {
+			req := &GetConfiguredPeersRequest{}
+			res := &GetConfiguredPeersResponse{}
+			if err := json.Unmarshal(in, &req); err != nil {
+				return nil, err
+			}
+			if err := a.getConfiguredPeersHandler(req, res); err != nil {
+				return nil, err
+			}
+			return res, nil
+		},
+	)
 	_ = a.AddHandler(
 		"getDHT", "Show known DHT entries", []string{},
 		func(in json.RawMessage) (interface{}, error) {
@@ -201,6 +233,146 @@ func (a *AdminSocket) SetupAdminHandlers() {
 			return res, nil
 		},
 	)
+	_ = a.AddHandler(
+		"getListeners", "Show active listeners and the number of peerings accepted by each", []string{},
+		func(in json.RawMessage) (interface{}, error) {
+			req := &GetListenersRequest{}
+			res := &GetListenersResponse{}
+			if err := json.Unmarshal(in, &req); err != nil {
+				return nil, err
+			}
+			if err := a.getListenersHandler(req, res); err != nil {
+				return nil, err
+			}
+			return res, nil
+		},
+	)
+	_ = a.AddHandler(
+		"addListener", "Start a listener from a URI", []string{"uri", "interface"},
+		func(in json.RawMessage) (interface{}, error) {
+			req := &AddListenerRequest{}
+			res := &AddListenerResponse{}
+			if err := json.Unmarshal(in, &req); err != nil {
+				return nil, err
+			}
+			if err := a.addListenerHandler(req, res); err != nil {
+				return nil, err
+			}
+			return res, nil
+		},
+	)
+	_ = a.AddHandler(
+		"removeListener", "Stop the listener that was started from a URI", []string{"uri", "interface"},
+		func(in json.RawMessage) (interface{}, error) {
+			req := &RemoveListenerRequest{}
+			res := &RemoveListenerResponse{}
+			if err := json.Unmarshal(in, &req); err != nil {
+				return nil, err
+			}
+			if err := a.removeListenerHandler(req, res); err != nil {
+				return nil, err
+			}
+			return res, nil
+		},
+	)
+	_ = a.AddHandler(
+		"blockPeer", "Block peerings with a public key, or inbound connections from an IP address or prefix", []string{"key", "address"},
+		func(in json.RawMessage) (interface{}, error) {
+			req := &BlockPeerRequest{}
+			res := &BlockPeerResponse{}
+			if err := json.Unmarshal(in, &req); err != nil {
+				return nil, err
+			}
+			if err := a.blockPeerHandler(req, res); err != nil {
+				return nil, err
+			}
+			return res, nil
+		},
+	)
+	_ = a.AddHandler(
+		"unblockPeer", "Remove a public key, IP address or prefix from the blocklist, or lift a temporary ban", []string{"key", "address"},
+		func(in json.RawMessage) (interface{}, error) {
+			req := &UnblockPeerRequest{}
+			res := &UnblockPeerResponse{}
+			if err := json.Unmarshal(in, &req); err != nil {
+				return nil, err
+			}
+			if err := a.unblockPeerHandler(req, res); err != nil {
+				return nil, err
+			}
+			return res, nil
+		},
+	)
+	_ = a.AddHandler(
+		"getBlocked", "Show blocked public keys and addresses, and temporarily banned sources", []string{},
+		func(in json.RawMessage) (interface{}, error) {
+			req := &GetBlockedRequest{}
+			res := &GetBlockedResponse{}
+			if err := json.Unmarshal(in, &req); err != nil {
+				return nil, err
+			}
+			if err := a.getBlockedHandler(req, res); err != nil {
+				return nil, err
+			}
+			return res, nil
+		},
+	)
+	_ = a.AddHandler(
+		"addAllowedKey", "Allow inbound peerings from a public key", []string{"key"},
+		func(in json.RawMessage) (interface{}, error) {
+			req := &AddAllowedKeyRequest{}
+			res := &AddAllowedKeyResponse{}
+			if err := json.Unmarshal(in, &req); err != nil {
+				return nil, err
+			}
+			if err := a.addAllowedKeyHandler(req, res); err != nil {
+				return nil, err
+			}
+			return res, nil
+		},
+	)
+	_ = a.AddHandler(
+		"removeAllowedKey", "Stop allowing inbound peerings from a public key and disconnect them", []string{"key"},
+		func(in json.RawMessage) (interface{}, error) {
+			req := &RemoveAllowedKeyRequest{}
+			res := &RemoveAllowedKeyResponse{}
+			if err := json.Unmarshal(in, &req); err != nil {
+				return nil, err
+			}
+			if err := a.removeAllowedKeyHandler(req, res); err != nil {
+				return nil, err
+			}
+			return res, nil
+		},
+	)
+	_ = a.AddHandler(
+		"getAllowedKeys", "Show public keys that are allowed to peer inbound, or all if empty", []string{},
+		func(in json.RawMessage) (interface{}, error) {
+			req := &GetAllowedKeysRequest{}
+			res := &GetAllowedKeysResponse{}
+			if err := json.Unmarshal(in, &req); err != nil {
+				return nil, err
+			}
+			if err := a.getAllowedKeysHandler(req, res); err != nil {
+				return nil, err
+			}
+			return res, nil
+		},
+	)
+	_ = a.AddHandler(
+		"subscribe", "Stream events, such as peers connecting and disconnecting, until the connection is closed", []string{"types"},
+		func(in json.RawMessage) (interface{}, error) {
+			req := &SubscribeRequest{}
+			res := &SubscribeResponse{}
+			if err := json.Unmarshal(in, &req); err != nil {
+				return nil, err
+			}
+			if err := a.subscribeHandler(req, res); err != nil {
+				return nil, err
+			}
+			return res, nil
+		},
+	)
 	//_ = a.AddHandler("getNodeInfo", []string{"key"}, t.proto.nodeinfo.nodeInfoAdminHandler)
 	//_ = a.AddHandler("debug_remoteGetSelf", []string{"key"}, t.proto.getSelfHandler)
 	//_ = a.AddHandler("debug_remoteGetPeers", []string{"key"}, t.proto.getPeersHandler)
@@ -224,12 +396,15 @@ func (a *AdminSocket) Stop() error {
 	if a == nil {
 		return nil
 	}
+	select {
+	case <-a.done:
+	default:
+		close(a.done)
+	}
+	if a.http != nil {
+		_ = a.http.Close()
+	}
 	if a.listener != nil {
-		select {
-		case <-a.done:
-		default:
-			close(a.done)
-		}
 		return a.listener.Close()
 	}
 	return nil
@@ -268,6 +443,11 @@ func (a *AdminSocket) listen() {
 			}
 		case "tcp":
 			a.listener, err = net.Listen("tcp", u.Host)
+		case "tls":
+			var config *tls.Config
+			if config, err = a.tlsConfig(); err == nil {
+				a.listener, err = tls.Listen("tcp", u.Host, config)
+			}
 		default:
 			// err = errors.New(fmt.Sprint("protocol not supported: ", u.Scheme))
 			a.listener, err = net.Listen("tcp", listenaddr)
@@ -279,9 +459,11 @@ func (a *AdminSocket) listen() {
 		a.log.Errorf("Admin socket failed to listen: %v", err)
 		os.Exit(1)
 	}
-	a.log.Infof("%s admin socket listening on %s",
-		strings.ToUpper(a.listener.Addr().Network()),
-		a.listener.Addr().String())
+	network := strings.ToUpper(a.listener.Addr().Network())
+	if u != nil && strings.EqualFold(u.Scheme, "tls") {
+		network = "TLS"
+	}
+	a.log.Infof("%s admin socket listening on %s", network, a.listener.Addr().String())
 	defer a.listener.Close()
 	for {
 		conn, err := a.listener.Accept()
@@ -299,6 +481,36 @@ func (a *AdminSocket) listen() {
 	}
 }
 
+// tlsConfig loads the certificate and key for a tls:// admin socket, and the
+// CAs to check client certificates against, if set. Clients must present a
+// certificate signed by one of them if there are any.
+func (a *AdminSocket) tlsConfig() (*tls.Config, error) {
+	if a.config.tlsCertificate == "" || a.config.tlsKey == "" {
+		return nil, errors.New("a certificate and key are needed for a tls:// admin socket")
+	}
+	cert, err := tls.LoadX509KeyPair(a.config.tlsCertificate, a.config.tlsKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load certificate: %w", err)
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if a.config.tlsClientCA != "" {
+		pem, err := os.ReadFile(a.config.tlsClientCA)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in client CA %s", a.config.tlsClientCA)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
+
 // handleRequest calls the request handler for each request sent to the admin API.
 func (a *AdminSocket) handleRequest(conn net.Conn) {
 	decoder := json.NewDecoder(conn)
@@ -338,24 +550,31 @@ func (a *AdminSocket) handleRequest(conn net.Conn) {
 			if req.Name == "" {
 				return fmt.Errorf("No request specified")
 			}
-			reqname := strings.ToLower(req.Name)
-			handler, ok := a.handlers[reqname]
-			if !ok {
-				return fmt.Errorf("Unknown action '%s', try 'list' for help", reqname)
-			}
-			res, err := handler.handler(req.Arguments)
-			if err != nil {
+			if resp.Response, err = a.handle(&req, conn.RemoteAddr()); err != nil {
 				return err
 			}
-			if resp.Response, err = json.Marshal(res); err != nil {
-				return fmt.Errorf("Failed to marshal response: %w", err)
-			}
 			resp.Status = "success"
 			return nil
 		}(); err != nil {
 			resp.Status = "error"
 			resp.Error = err.Error()
 		}
+		if resp.Status == "success" && strings.EqualFold(req.Name, "subscribe") {
+			// The connection now belongs to the subscription. Subscribe before
+			// replying, so that no events are missed after the reply.
+			var res SubscribeResponse
+			if err = json.Unmarshal(resp.Response, &res); err != nil {
+				break
+			}
+			events, unsubscribe := a.core.Subscribe()
+			defer unsubscribe()
+			if err = encoder.Encode(resp); err != nil {
+				a.log.Debugln("Encode error:", err)
+				break
+			}
+			a.streamEvents(conn, encoder, events, res.Types)
+			break
+		}
 		if err = encoder.Encode(resp); err != nil {
 			a.log.Debugln("Encode error:", err)
 		}
@@ -367,6 +586,29 @@ func (a *AdminSocket) handleRequest(conn net.Conn) {
 	}
 }
 
+// handle checks that a request is authorised and calls its handler, returning
+// the encoded response. It is shared by the admin socket and HTTP gateway.
+func (a *AdminSocket) handle(req *AdminSocketRequest, from net.Addr) (json.RawMessage, error) {
+	reqname := strings.ToLower(req.Name)
+	if err := a.authorise(req, reqname); err != nil {
+		a.log.Debugf("Admin socket refused '%s' from %s: %s", reqname, from, err)
+		return nil, err
+	}
+	handler, ok := a.handlers[reqname]
+	if !ok {
+		return nil, fmt.Errorf("Unknown action '%s', try 'list' for help", reqname)
+	}
+	res, err := handler.handler(req.Arguments)
+	if err != nil {
+		return nil, err
+	}
+	response, err := json.Marshal(res)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to marshal response: %w", err)
+	}
+	return response, nil
+}
+
 type DataUnit uint64
 
 func (d DataUnit) String() string {
@@ -0,0 +1,50 @@
+package admin
+
+import (
+	"encoding/hex"
+	"sort"
+)
+
+type AddAllowedKeyRequest struct {
+	PublicKey string `json:"key"`
+}
+
+type AddAllowedKeyResponse struct{}
+
+func (a *AdminSocket) addAllowedKeyHandler(req *AddAllowedKeyRequest, res *AddAllowedKeyResponse) error {
+	key, err := decodePublicKey(req.PublicKey)
+	if err != nil {
+		return err
+	}
+	return a.core.AddAllowedPublicKey(key)
+}
+
+type RemoveAllowedKeyRequest struct {
+	PublicKey string `json:"key"`
+}
+
+type RemoveAllowedKeyResponse struct{}
+
+func (a *AdminSocket) removeAllowedKeyHandler(req *RemoveAllowedKeyRequest, res *RemoveAllowedKeyResponse) error {
+	key, err := decodePublicKey(req.PublicKey)
+	if err != nil {
+		return err
+	}
+	return a.core.RemoveAllowedPublicKey(key)
+}
+
+type GetAllowedKeysRequest struct{}
+
+type GetAllowedKeysResponse struct {
+	PublicKeys []string `json:"keys"`
+}
+
+func (a *AdminSocket) getAllowedKeysHandler(req *GetAllowedKeysRequest, res *GetAllowedKeysResponse) error {
+	keys := a.core.GetAllowedPublicKeys()
+	res.PublicKeys = make([]string, 0, len(keys))
+	for _, key := range keys {
+		res.PublicKeys = append(res.PublicKeys, hex.EncodeToString(key))
+	}
+	sort.Strings(res.PublicKeys)
+	return nil
+}
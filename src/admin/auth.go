@@ -0,0 +1,84 @@
+package admin
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// The roles that can be given to an admin token, and the commands that each of
+// them may call. Commands are matched case-insensitively, with * as a wildcard.
+const (
+	RoleAdmin    = "admin"     // may call any command
+	RoleReadOnly = "read-only" // may only call get* commands and subscribe, e.g. for monitoring
+)
+
+var roleCommands = map[string][]string{
+	RoleAdmin:    {"*"},
+	RoleReadOnly: {"list", "get*", "subscribe"},
+}
+
+var (
+	errAuthRequired = errors.New("Authentication required")
+	errAuthFailed   = errors.New("Authentication failed")
+	errAuthDenied   = errors.New("Permission denied")
+)
+
+// authToken holds the commands that a token may call. Tokens are looked up by
+// their hash, so that the lookup doesn't leak the token through its timing.
+type authToken struct {
+	role     string
+	commands []string
+}
+
+// allows returns true if the token may call the named command.
+func (t *authToken) allows(name string) bool {
+	name = strings.ToLower(name)
+	for _, pattern := range t.commands {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// addToken adds a token from the setup options. Authentication is required as
+// soon as any token is configured, even an invalid one, so that a mistake in
+// the configuration can't leave the admin socket open.
+func (a *AdminSocket) addToken(t AuthToken) {
+	a.config.authRequired = true
+	if t.Token == "" {
+		a.log.Warnln("Ignoring admin token with no token set")
+		return
+	}
+	token := &authToken{role: t.Role}
+	commands, ok := roleCommands[t.Role]
+	if !ok && t.Role != "" {
+		a.log.Warnf("Admin token has unknown role %q, only allowing the commands that are listed for it", t.Role)
+	}
+	for _, command := range append(commands, t.Commands...) {
+		token.commands = append(token.commands, strings.ToLower(command))
+	}
+	a.config.tokens[sha256.Sum256([]byte(t.Token))] = token
+}
+
+// authorise checks that the request carries a token that may call the named
+// command, if authentication is required.
+func (a *AdminSocket) authorise(req *AdminSocketRequest, name string) error {
+	if !a.config.authRequired {
+		return nil
+	}
+	if req.Auth == "" {
+		return errAuthRequired
+	}
+	token, ok := a.config.tokens[sha256.Sum256([]byte(req.Auth))]
+	if !ok {
+		return errAuthFailed
+	}
+	if !token.allows(name) {
+		return fmt.Errorf("%w for '%s' with role '%s'", errAuthDenied, name, token.role)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package admin
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/gologme/log"
+)
+
+func newTestAdminSocket(opts ...SetupOption) *AdminSocket {
+	a := &AdminSocket{
+		log:      log.New(io.Discard, "", 0),
+		handlers: make(map[string]handler),
+	}
+	a.config.tokens = make(map[[32]byte]*authToken)
+	for _, opt := range opts {
+		a._applyOption(opt)
+	}
+	for _, name := range []string{"getSelf", "addPeer", "removePeer"} {
+		_ = a.AddHandler(name, "", nil, func(_ json.RawMessage) (interface{}, error) {
+			return struct{}{}, nil
+		})
+	}
+	return a
+}
+
+func adminRequest(t *testing.T, a *AdminSocket, req *AdminSocketRequest) *AdminSocketResponse {
+	t.Helper()
+	client, server := net.Pipe()
+	defer client.Close()
+	go a.handleRequest(server)
+	if err := json.NewEncoder(client).Encode(req); err != nil {
+		t.Fatal(err)
+	}
+	var res AdminSocketResponse
+	if err := json.NewDecoder(client).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+	return &res
+}
+
+func TestAdminSocket_Auth(t *testing.T) {
+	a := newTestAdminSocket(
+		AuthToken{Token: "noc", Role: RoleReadOnly},
+		AuthToken{Token: "ops", Role: RoleReadOnly, Commands: []string{"addPeer"}},
+		AuthToken{Token: "root", Role: RoleAdmin},
+	)
+	for _, test := range []struct {
+		token, request string
+		allowed        bool
+	}{
+		{"", "getSelf", false},
+		{"wrong", "getSelf", false},
+		{"noc", "getSelf", true},
+		{"noc", "GETSELF", true},
+		{"noc", "addPeer", false},
+		{"ops", "addPeer", true},
+		{"ops", "removePeer", false},
+		{"root", "removePeer", true},
+	} {
+		res := adminRequest(t, a, &AdminSocketRequest{Name: test.request, Auth: test.token})
+		if allowed := res.Status == "success"; allowed != test.allowed {
+			t.Errorf("token %q calling %s: expected allowed=%t, got %q %s",
+				test.token, test.request, test.allowed, res.Status, res.Error)
+		}
+	}
+}
+
+func TestAdminSocket_NoAuth(t *testing.T) {
+	a := newTestAdminSocket()
+	if res := adminRequest(t, a, &AdminSocketRequest{Name: "addPeer"}); res.Status != "success" {
+		t.Fatalf("expected no authentication without tokens, got %q %s", res.Status, res.Error)
+	}
+
+	// A token that is set but empty must not turn authentication off.
+	a = newTestAdminSocket(AuthToken{Role: RoleAdmin})
+	if res := adminRequest(t, a, &AdminSocketRequest{Name: "getSelf"}); res.Status == "success" {
+		t.Fatal("expected authentication to be required with an invalid token configured")
+	}
+}
@@ -0,0 +1,66 @@
+package admin
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"fmt"
+)
+
+type BlockPeerRequest struct {
+	PublicKey string `json:"key,omitempty"`
+	Address   string `json:"address,omitempty"`
+}
+
+type BlockPeerResponse struct{}
+
+func (a *AdminSocket) blockPeerHandler(req *BlockPeerRequest, res *BlockPeerResponse) error {
+	switch {
+	case req.PublicKey != "" && req.Address != "":
+		return fmt.Errorf("specify either a key or an address, not both")
+	case req.PublicKey != "":
+		key, err := decodePublicKey(req.PublicKey)
+		if err != nil {
+			return err
+		}
+		return a.core.BlockPublicKey(key)
+	case req.Address != "":
+		return a.core.BlockAddress(req.Address)
+	default:
+		return fmt.Errorf("a key or an address is required")
+	}
+}
+
+type UnblockPeerRequest struct {
+	PublicKey string `json:"key,omitempty"`
+	Address   string `json:"address,omitempty"`
+}
+
+type UnblockPeerResponse struct{}
+
+func (a *AdminSocket) unblockPeerHandler(req *UnblockPeerRequest, res *UnblockPeerResponse) error {
+	switch {
+	case req.PublicKey != "" && req.Address != "":
+		return fmt.Errorf("specify either a key or an address, not both")
+	case req.PublicKey != "":
+		key, err := decodePublicKey(req.PublicKey)
+		if err != nil {
+			return err
+		}
+		return a.core.UnblockPublicKey(key)
+	case req.Address != "":
+		return a.core.UnblockAddress(req.Address)
+	default:
+		return fmt.Errorf("a key or an address is required")
+	}
+}
+
+func decodePublicKey(key string) (ed25519.PublicKey, error) {
+	bs, err := hex.DecodeString(key)
+	if err != nil {
+		return nil, fmt.Errorf("key contains invalid hex characters")
+	}
+	if len(bs) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("key is incorrect length")
+	}
+	return ed25519.PublicKey(bs), nil
+}
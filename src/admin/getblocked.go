@@ -0,0 +1,42 @@
+package admin
+
+import (
+	"encoding/hex"
+	"sort"
+	"time"
+)
+
+type GetBlockedRequest struct{}
+
+type GetBlockedResponse struct {
+	PublicKeys []string   `json:"keys"`
+	Addresses  []string   `json:"addresses"`
+	Bans       []BanEntry `json:"bans"`
+}
+
+type BanEntry struct {
+	Source    string  `json:"source"`
+	Remaining float64 `json:"remaining"`
+}
+
+func (a *AdminSocket) getBlockedHandler(req *GetBlockedRequest, res *GetBlockedResponse) error {
+	blocked := a.core.GetBlocked()
+	res.PublicKeys = make([]string, 0, len(blocked.PublicKeys))
+	for _, key := range blocked.PublicKeys {
+		res.PublicKeys = append(res.PublicKeys, hex.EncodeToString(key))
+	}
+	res.Addresses = append(make([]string, 0, len(blocked.Addresses)), blocked.Addresses...)
+	res.Bans = make([]BanEntry, 0, len(blocked.Bans))
+	for _, ban := range blocked.Bans {
+		res.Bans = append(res.Bans, BanEntry{
+			Source:    ban.Source,
+			Remaining: time.Until(ban.Until).Seconds(),
+		})
+	}
+	sort.Strings(res.PublicKeys)
+	sort.Strings(res.Addresses)
+	sort.Slice(res.Bans, func(i, j int) bool {
+		return res.Bans[i].Source < res.Bans[j].Source
+	})
+	return nil
+}
@@ -0,0 +1,47 @@
+package admin
+
+import (
+	"sort"
+	"time"
+)
+
+type GetConfiguredPeersRequest struct {
+}
+
+type GetConfiguredPeersResponse struct {
+	Peers []ConfiguredPeerEntry `json:"peers"`
+}
+
+type ConfiguredPeerEntry struct {
+	URI       string  `json:"uri"`
+	Interface string  `json:"interface,omitempty"`
+	Connected bool    `json:"connected"`
+	Failures  uint64  `json:"failures"`
+	NextRetry float64 `json:"next_retry,omitempty"`
+	Source    string  `json:"source,omitempty"`
+}
+
+func (a *AdminSocket) getConfiguredPeersHandler(req *GetConfiguredPeersRequest, res *GetConfiguredPeersResponse) error {
+	peers := a.core.GetConfiguredPeers()
+	res.Peers = make([]ConfiguredPeerEntry, 0, len(peers))
+	for _, p := range peers {
+		entry := ConfiguredPeerEntry{
+			URI:       p.URI,
+			Interface: p.SourceInterface,
+			Connected: p.Connected,
+			Failures:  uint64(p.Failures),
+			Source:    p.Source,
+		}
+		if !p.NextRetry.IsZero() {
+			entry.NextRetry = time.Until(p.NextRetry).Seconds()
+		}
+		res.Peers = append(res.Peers, entry)
+	}
+	sort.Slice(res.Peers, func(i, j int) bool {
+		if res.Peers[i].URI == res.Peers[j].URI {
+			return res.Peers[i].Interface < res.Peers[j].Interface
+		}
+		return res.Peers[i].URI < res.Peers[j].URI
+	})
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/hex"
 	"net"
 	"sort"
+	"time"
 
 	"github.com/yggdrasil-network/yggdrasil-go/src/address"
 )
@@ -16,15 +17,27 @@ type GetPeersResponse struct {
 }
 
 type PeerEntry struct {
-	IPAddress string   `json:"address"`
-	PublicKey string   `json:"key"`
-	Port      uint64   `json:"port"`
-	Priority  uint64   `json:"priority"`
-	Coords    []uint64 `json:"coords"`
-	Remote    string   `json:"remote"`
-	RXBytes   DataUnit `json:"bytes_recvd"`
-	TXBytes   DataUnit `json:"bytes_sent"`
-	Uptime    float64  `json:"uptime"`
+	IPAddress        string   `json:"address"`
+	PublicKey        string   `json:"key"`
+	Port             uint64   `json:"port"`
+	Priority         uint64   `json:"priority"`
+	Coords           []uint64 `json:"coords"`
+	Remote           string   `json:"remote"`
+	Name             string   `json:"name,omitempty"`
+	RXBytes          DataUnit `json:"bytes_recvd"`
+	TXBytes          DataUnit `json:"bytes_sent"`
+	Uptime           float64  `json:"uptime"`
+	RTT              float64  `json:"rtt"`
+	Jitter           float64  `json:"jitter"`
+	LastSeen         float64  `json:"last_seen"`
+	MaxUp            DataUnit `json:"max_upload,omitempty"`
+	MaxDown          DataUnit `json:"max_download,omitempty"`
+	ThrottledRXBytes DataUnit `json:"throttled_bytes_recvd"`
+	ThrottledTXBytes DataUnit `json:"throttled_bytes_sent"`
+	Compressed       bool     `json:"compressed,omitempty"`
+	CompressedRX     DataUnit `json:"compressed_bytes_recvd,omitempty"`
+	CompressedTX     DataUnit `json:"compressed_bytes_sent,omitempty"`
+	CompressionRatio float64  `json:"compression_ratio,omitempty"`
 }
 
 func (a *AdminSocket) getPeersHandler(req *GetPeersRequest, res *GetPeersResponse) error {
@@ -32,17 +45,32 @@ func (a *AdminSocket) getPeersHandler(req *GetPeersRequest, res *GetPeersRespons
 	res.Peers = make([]PeerEntry, 0, len(peers))
 	for _, p := range peers {
 		addr := address.AddrForKey(p.Key)
-		res.Peers = append(res.Peers, PeerEntry{
-			IPAddress: net.IP(addr[:]).String(),
-			PublicKey: hex.EncodeToString(p.Key),
-			Port:      p.Port,
-			Priority:  uint64(p.Priority), // can't be uint8 thanks to gobind
-			Coords:    p.Coords,
-			Remote:    p.Remote,
-			RXBytes:   DataUnit(p.RXBytes),
-			TXBytes:   DataUnit(p.TXBytes),
-			Uptime:    p.Uptime.Seconds(),
-		})
+		peer := PeerEntry{
+			IPAddress:        net.IP(addr[:]).String(),
+			PublicKey:        hex.EncodeToString(p.Key),
+			Port:             p.Port,
+			Priority:         uint64(p.Priority), // can't be uint8 thanks to gobind
+			Coords:           p.Coords,
+			Remote:           p.Remote,
+			Name:             p.Name,
+			RXBytes:          DataUnit(p.RXBytes),
+			TXBytes:          DataUnit(p.TXBytes),
+			Uptime:           p.Uptime.Seconds(),
+			RTT:              p.RTT.Seconds(),
+			Jitter:           p.Jitter.Seconds(),
+			MaxUp:            DataUnit(p.MaxUpload),
+			MaxDown:          DataUnit(p.MaxDownload),
+			ThrottledRXBytes: DataUnit(p.ThrottledRXBytes),
+			ThrottledTXBytes: DataUnit(p.ThrottledTXBytes),
+			Compressed:       p.Compressed,
+			CompressedRX:     DataUnit(p.CompressedRXBytes),
+			CompressedTX:     DataUnit(p.CompressedTXBytes),
+			CompressionRatio: p.CompressionRatio,
+		}
+		if !p.LastSeen.IsZero() {
+			peer.LastSeen = time.Since(p.LastSeen).Seconds()
+		}
+		res.Peers = append(res.Peers, peer)
 	}
 	sort.Slice(res.Peers, func(i, j int) bool {
 		if res.Peers[i].Port == res.Peers[j].Port {
@@ -0,0 +1,255 @@
+package admin
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The largest request body that the HTTP gateway will accept.
+const httpMaxRequestSize = 1 << 20
+
+// APISchema is returned from /api on the HTTP gateway, and describes the
+// commands that can be called and how.
+type APISchema struct {
+	Commands []APICommand `json:"commands"`
+}
+
+type APICommand struct {
+	Command     string   `json:"command"`
+	Description string   `json:"description"`
+	Path        string   `json:"path"`
+	Methods     []string `json:"methods"`
+	Fields      []string `json:"fields,omitempty"`
+}
+
+// allowsGet returns true if a command can be called with GET as well as POST,
+// which is the case for get* commands that take no arguments.
+func (h handler) allowsGet(name string) bool {
+	return len(h.args) == 0 && (name == "list" || strings.HasPrefix(name, "get"))
+}
+
+// listenHTTP starts the HTTP gateway, which makes every admin handler available
+// as POST /api/<command>. The arguments are given as a JSON object in the body,
+// with a Content-Type of application/json, and the token, if one is needed, in
+// an "Authorization: Bearer" header.
+func (a *AdminSocket) listenHTTP() error {
+	u, err := url.Parse(a.config.httpListenAddr)
+	if err != nil {
+		return fmt.Errorf("invalid admin HTTP listen address: %w", err)
+	}
+	listener, err := net.Listen("tcp", u.Host)
+	if err != nil {
+		return fmt.Errorf("admin HTTP gateway failed to listen: %w", err)
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http":
+	case "https":
+		config, err := a.tlsConfig()
+		if err != nil {
+			_ = listener.Close()
+			return err
+		}
+		listener = tls.NewListener(listener, config)
+	default:
+		_ = listener.Close()
+		return fmt.Errorf("admin HTTP listen address %q must be http or https", a.config.httpListenAddr)
+	}
+	a.http = &http.Server{
+		Handler:           a.httpHandler(),
+		ReadHeaderTimeout: time.Second * 10,
+	}
+	a.log.Infof("%s admin HTTP gateway listening on %s", strings.ToUpper(u.Scheme), listener.Addr())
+	go func() {
+		if err := a.http.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			a.log.Errorf("Admin HTTP gateway failed: %s", err)
+		}
+	}()
+	return nil
+}
+
+// httpHandler returns the handler for the paths that the HTTP gateway serves.
+func (a *AdminSocket) httpHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api", a.httpSchema)
+	mux.HandleFunc("/api/", a.httpCommand)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if status, err := a.httpCheckRequest(r); err != nil {
+			a.httpReply(w, status, &AdminSocketResponse{Status: "error", Error: err.Error()})
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// httpCheckRequest refuses requests that may have come from a web page rather
+// than from an admin client, returning the status code to reply with. Without
+// this, when no tokens are configured, any web page could make the browser
+// send a request to the gateway, e.g. a text/plain POST to /api/addPeer, which
+// needs no CORS preflight. Browsers always send an Origin header with those.
+// A web page could also point its own host name at the gateway with DNS
+// rebinding to read the responses, which is why the Host header must be an IP
+// address, localhost, or the host name from the listen address.
+func (a *AdminSocket) httpCheckRequest(r *http.Request) (int, error) {
+	if r.Header.Get("Origin") != "" {
+		return http.StatusForbidden, errors.New("Cross-origin requests are not allowed")
+	}
+	if !a.httpAllowedHost(r.Host) {
+		return http.StatusForbidden, fmt.Errorf("Host %q is not allowed", r.Host)
+	}
+	if r.Method == http.MethodPost {
+		mediatype, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil || mediatype != "application/json" {
+			return http.StatusUnsupportedMediaType, errors.New("Content-Type must be application/json")
+		}
+	}
+	return 0, nil
+}
+
+// httpAllowedHost returns true if the host from a request's Host header is one
+// that the gateway can be reached at without DNS rebinding.
+func (a *AdminSocket) httpAllowedHost(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	if net.ParseIP(host) != nil || strings.EqualFold(host, "localhost") {
+		return true
+	}
+	u, err := url.Parse(a.config.httpListenAddr)
+	return err == nil && u.Hostname() != "" && strings.EqualFold(u.Hostname(), host)
+}
+
+// httpSchema answers /api with the commands that are available.
+func (a *AdminSocket) httpSchema(w http.ResponseWriter, r *http.Request) {
+	req := &AdminSocketRequest{Name: "list", Auth: httpToken(r)}
+	if err := a.authorise(req, req.Name); err != nil {
+		a.httpError(w, err)
+		return
+	}
+	schema := &APISchema{}
+	for name, handler := range a.handlers {
+		if name == "subscribe" {
+			continue
+		}
+		methods := []string{http.MethodPost}
+		if handler.allowsGet(name) {
+			methods = append(methods, http.MethodGet)
+		}
+		schema.Commands = append(schema.Commands, APICommand{
+			Command:     name,
+			Description: handler.desc,
+			Path:        "/api/" + name,
+			Methods:     methods,
+			Fields:      handler.args,
+		})
+	}
+	sort.Slice(schema.Commands, func(i, j int) bool {
+		return schema.Commands[i].Command < schema.Commands[j].Command
+	})
+	response, err := json.Marshal(schema)
+	if err != nil {
+		a.httpError(w, err)
+		return
+	}
+	a.httpReply(w, http.StatusOK, &AdminSocketResponse{Status: "success", Response: response})
+}
+
+// httpCommand answers /api/<command> by calling the handler for the command.
+func (a *AdminSocket) httpCommand(w http.ResponseWriter, r *http.Request) {
+	defer func() {
+		if r := recover(); r != nil {
+			a.log.Debugln("Admin HTTP gateway error:", r)
+			a.httpReply(w, http.StatusBadRequest, &AdminSocketResponse{
+				Status: "error",
+				Error:  "Check your syntax and input types",
+			})
+		}
+	}()
+	name := strings.ToLower(strings.TrimPrefix(r.URL.Path, "/api/"))
+	handler, ok := a.handlers[name]
+	if !ok || name == "subscribe" { // events are only streamed on the admin socket
+		a.httpReply(w, http.StatusNotFound, &AdminSocketResponse{
+			Status: "error",
+			Error:  fmt.Sprintf("Unknown action '%s', see /api for help", name),
+		})
+		return
+	}
+	req := &AdminSocketRequest{
+		Name:      name,
+		Arguments: json.RawMessage("{}"),
+		Auth:      httpToken(r),
+	}
+	switch {
+	case r.Method == http.MethodPost:
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, httpMaxRequestSize))
+		if err != nil {
+			a.httpError(w, fmt.Errorf("Failed to read request: %w", err))
+			return
+		}
+		if len(strings.TrimSpace(string(body))) > 0 {
+			req.Arguments = body
+		}
+	case r.Method == http.MethodGet && handler.allowsGet(name):
+	default:
+		allow := http.MethodPost
+		if handler.allowsGet(name) {
+			allow = http.MethodGet + ", " + http.MethodPost
+		}
+		w.Header().Set("Allow", allow)
+		a.httpReply(w, http.StatusMethodNotAllowed, &AdminSocketResponse{
+			Status: "error",
+			Error:  fmt.Sprintf("Method %s not allowed for '%s'", r.Method, name),
+		})
+		return
+	}
+	from, _ := net.ResolveTCPAddr("tcp", r.RemoteAddr)
+	response, err := a.handle(req, from)
+	if err != nil {
+		a.httpError(w, err)
+		return
+	}
+	a.httpReply(w, http.StatusOK, &AdminSocketResponse{Status: "success", Response: response})
+}
+
+// httpError replies with an error, with a status code that reflects whether it
+// was an authentication problem.
+func (a *AdminSocket) httpError(w http.ResponseWriter, err error) {
+	status := http.StatusBadRequest
+	switch {
+	case errors.Is(err, errAuthRequired), errors.Is(err, errAuthFailed):
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		status = http.StatusUnauthorized
+	case errors.Is(err, errAuthDenied):
+		status = http.StatusForbidden
+	}
+	a.httpReply(w, status, &AdminSocketResponse{Status: "error", Error: err.Error()})
+}
+
+func (a *AdminSocket) httpReply(w http.ResponseWriter, status int, resp *AdminSocketResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(resp); err != nil {
+		a.log.Debugln("Admin HTTP gateway encode error:", err)
+	}
+}
+
+// httpToken returns the token from an "Authorization: Bearer" header.
+func httpToken(r *http.Request) string {
+	auth := r.Header.Get("Authorization")
+	if len(auth) > 7 && strings.EqualFold(auth[:7], "Bearer ") {
+		return strings.TrimSpace(auth[7:])
+	}
+	return ""
+}
@@ -0,0 +1,142 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAdminSocket_HTTP(t *testing.T) {
+	a := newTestAdminSocket(
+		AuthToken{Token: "noc", Role: RoleReadOnly},
+		AuthToken{Token: "root", Role: RoleAdmin},
+	)
+	_ = a.AddHandler("echo", "Return the arguments", []string{"value"}, func(in json.RawMessage) (interface{}, error) {
+		return in, nil
+	})
+	server := httptest.NewServer(a.httpHandler())
+	defer server.Close()
+
+	request := func(method, path, token, body string) (int, *AdminSocketResponse) {
+		t.Helper()
+		req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		if method == http.MethodPost {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var res AdminSocketResponse
+		if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, &res
+	}
+
+	for _, test := range []struct {
+		method, path, token string
+		status              int
+	}{
+		{http.MethodGet, "/api/getSelf", "noc", http.StatusOK},
+		{http.MethodPost, "/api/getSelf", "noc", http.StatusOK},
+		{http.MethodGet, "/api/getSelf", "", http.StatusUnauthorized},
+		{http.MethodGet, "/api/getSelf", "wrong", http.StatusUnauthorized},
+		{http.MethodPost, "/api/addPeer", "noc", http.StatusForbidden},
+		{http.MethodPost, "/api/addPeer", "root", http.StatusOK},
+		{http.MethodGet, "/api/addPeer", "root", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/api/missing", "root", http.StatusNotFound},
+	} {
+		if status, res := request(test.method, test.path, test.token, ""); status != test.status {
+			t.Errorf("%s %s with token %q: expected status %d, got %d %s",
+				test.method, test.path, test.token, test.status, status, res.Error)
+		}
+	}
+
+	// The body is passed to the handler as the arguments.
+	status, res := request(http.MethodPost, "/api/echo", "root", `{"value":"hello"}`)
+	if status != http.StatusOK || res.Status != "success" {
+		t.Fatalf("echo failed: %d %s", status, res.Error)
+	}
+	var echo struct{ Value string }
+	if err := json.Unmarshal(res.Response, &echo); err != nil || echo.Value != "hello" {
+		t.Fatalf("unexpected echo response %s", res.Response)
+	}
+
+	// The schema lists the commands and how they can be called.
+	status, res = request(http.MethodGet, "/api", "noc", "")
+	if status != http.StatusOK {
+		t.Fatalf("schema failed: %d %s", status, res.Error)
+	}
+	var schema APISchema
+	if err := json.Unmarshal(res.Response, &schema); err != nil {
+		t.Fatal(err)
+	}
+	methods := map[string]int{}
+	for _, command := range schema.Commands {
+		methods[command.Path] = len(command.Methods)
+	}
+	if methods["/api/getself"] != 2 || methods["/api/addpeer"] != 1 || methods["/api/echo"] != 1 {
+		t.Fatalf("unexpected schema %+v", schema)
+	}
+}
+
+// TestAdminSocket_HTTPCrossSite checks that requests that a web page could make
+// the browser send are refused, even with no tokens configured.
+func TestAdminSocket_HTTPCrossSite(t *testing.T) {
+	a := newTestAdminSocket(HTTPListenAddress("http://admin.example:9002"))
+	server := httptest.NewServer(a.httpHandler())
+	defer server.Close()
+
+	for _, test := range []struct {
+		name        string
+		method      string
+		host        string
+		origin      string
+		contentType string
+		status      int
+	}{
+		{"json", http.MethodPost, "", "", "application/json", http.StatusOK},
+		{"json charset", http.MethodPost, "", "", "application/json; charset=utf-8", http.StatusOK},
+		{"text/plain", http.MethodPost, "", "", "text/plain", http.StatusUnsupportedMediaType},
+		{"form", http.MethodPost, "", "", "application/x-www-form-urlencoded", http.StatusUnsupportedMediaType},
+		{"no content type", http.MethodPost, "", "", "", http.StatusUnsupportedMediaType},
+		{"origin", http.MethodPost, "", "http://evil.example", "application/json", http.StatusForbidden},
+		{"origin get", http.MethodGet, "", "http://evil.example", "", http.StatusForbidden},
+		{"rebound host", http.MethodGet, "evil.example:9002", "", "", http.StatusForbidden},
+		{"listen host", http.MethodGet, "admin.example:9002", "", "", http.StatusOK},
+		{"localhost", http.MethodGet, "localhost:9002", "", "", http.StatusOK},
+		{"ipv6", http.MethodGet, "[::1]:9002", "", "", http.StatusOK},
+	} {
+		req, err := http.NewRequest(test.method, server.URL+"/api/getSelf", strings.NewReader("{}"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if test.host != "" {
+			req.Host = test.host
+		}
+		if test.origin != "" {
+			req.Header.Set("Origin", test.origin)
+		}
+		if test.contentType != "" {
+			req.Header.Set("Content-Type", test.contentType)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != test.status {
+			t.Errorf("%s: expected status %d, got %d", test.name, test.status, resp.StatusCode)
+		}
+	}
+}
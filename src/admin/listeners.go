@@ -0,0 +1,60 @@
+package admin
+
+import (
+	"sort"
+)
+
+type GetListenersRequest struct{}
+
+type GetListenersResponse struct {
+	Listeners []ListenerEntry `json:"listeners"`
+}
+
+type ListenerEntry struct {
+	URI       string `json:"uri"`
+	Interface string `json:"interface,omitempty"`
+	Address   string `json:"address"`
+	Peers     uint64 `json:"peers"`
+}
+
+func (a *AdminSocket) getListenersHandler(req *GetListenersRequest, res *GetListenersResponse) error {
+	listeners := a.core.GetListeners()
+	res.Listeners = make([]ListenerEntry, 0, len(listeners))
+	for _, l := range listeners {
+		res.Listeners = append(res.Listeners, ListenerEntry{
+			URI:       l.URI,
+			Interface: l.SourceInterface,
+			Address:   l.Address,
+			Peers:     uint64(l.Peers),
+		})
+	}
+	sort.Slice(res.Listeners, func(i, j int) bool {
+		if res.Listeners[i].URI == res.Listeners[j].URI {
+			return res.Listeners[i].Interface < res.Listeners[j].Interface
+		}
+		return res.Listeners[i].URI < res.Listeners[j].URI
+	})
+	return nil
+}
+
+type AddListenerRequest struct {
+	Uri   string `json:"uri"`
+	Sintf string `json:"interface,omitempty"`
+}
+
+type AddListenerResponse struct{}
+
+func (a *AdminSocket) addListenerHandler(req *AddListenerRequest, res *AddListenerResponse) error {
+	return a.core.AddListener(req.Uri, req.Sintf)
+}
+
+type RemoveListenerRequest struct {
+	Uri   string `json:"uri"`
+	Sintf string `json:"interface,omitempty"`
+}
+
+type RemoveListenerResponse struct{}
+
+func (a *AdminSocket) removeListenerHandler(req *RemoveListenerRequest, res *RemoveListenerResponse) error {
+	return a.core.RemoveListener(req.Uri, req.Sintf)
+}
@@ -4,6 +4,16 @@ func (c *AdminSocket) _applyOption(opt SetupOption) {
 	switch v := opt.(type) {
 	case ListenAddress:
 		c.config.listenaddr = v
+	case HTTPListenAddress:
+		c.config.httpListenAddr = string(v)
+	case AuthToken:
+		c.addToken(v)
+	case TLSCertificate:
+		c.config.tlsCertificate = string(v)
+	case TLSKey:
+		c.config.tlsKey = string(v)
+	case TLSClientCA:
+		c.config.tlsClientCA = string(v)
 	}
 }
 
@@ -12,5 +22,19 @@ type SetupOption interface {
 }
 
 type ListenAddress string
+type HTTPListenAddress string // http:// or https:// address for the HTTP gateway
+type TLSCertificate string    // PEM file with the certificate for tls://
+type TLSKey string            // PEM file with the private key for tls://
+type TLSClientCA string       // PEM file with the CAs that client certificates must be signed by, if any
+type AuthToken struct {
+	Token    string
+	Role     string   // RoleAdmin or RoleReadOnly
+	Commands []string // commands allowed on top of those for the role
+}
 
-func (a ListenAddress) isSetupOption() {}
+func (a ListenAddress) isSetupOption()     {}
+func (a HTTPListenAddress) isSetupOption() {}
+func (a AuthToken) isSetupOption()         {}
+func (a TLSCertificate) isSetupOption()    {}
+func (a TLSKey) isSetupOption()            {}
+func (a TLSClientCA) isSetupOption()       {}
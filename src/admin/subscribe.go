@@ -0,0 +1,114 @@
+package admin
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/yggdrasil-network/yggdrasil-go/src/address"
+	"github.com/yggdrasil-network/yggdrasil-go/src/core"
+)
+
+// The event types that can be subscribed to.
+var eventTypes = []string{
+	core.EventPeerConnected,
+	core.EventPeerDisconnected,
+	core.EventHandshakeRejected,
+	core.EventSessionCreated,
+	core.EventSessionExpired,
+	core.EventRootChanged,
+	core.EventMulticastDiscovered,
+	core.EventConfigReloaded,
+}
+
+type SubscribeRequest struct {
+	Types string `json:"types,omitempty"` // comma-separated, or empty for all
+}
+
+type SubscribeResponse struct {
+	Types []string `json:"types"`
+}
+
+// EventEntry is sent to subscribers as the response of each event, with the
+// status "event".
+type EventEntry struct {
+	Type      string            `json:"type"`
+	Time      time.Time         `json:"time"`
+	PublicKey string            `json:"key,omitempty"`
+	IPAddress string            `json:"address,omitempty"`
+	Fields    map[string]string `json:"fields,omitempty"`
+}
+
+// subscribeHandler checks the event types that were asked for. The events are
+// streamed by streamEvents once the response has been sent.
+func (a *AdminSocket) subscribeHandler(req *SubscribeRequest, res *SubscribeResponse) error {
+	if req.Types == "" {
+		res.Types = append(res.Types, eventTypes...)
+		return nil
+	}
+	for _, t := range strings.Split(req.Types, ",") {
+		t = strings.ToLower(strings.TrimSpace(t))
+		known := false
+		for _, e := range eventTypes {
+			known = known || t == e
+		}
+		if !known {
+			return fmt.Errorf("unknown event type %q, expected one of %s", t, strings.Join(eventTypes, ", "))
+		}
+		res.Types = append(res.Types, t)
+	}
+	return nil
+}
+
+// streamEvents sends events of the given types from a subscription to an admin
+// socket connection until it is closed by the client or the admin socket is
+// stopped.
+func (a *AdminSocket) streamEvents(conn net.Conn, encoder *json.Encoder, events <-chan core.Event, types []string) {
+	wanted := make(map[string]struct{}, len(types))
+	for _, t := range types {
+		wanted[t] = struct{}{}
+	}
+	closed := make(chan struct{})
+	go func() {
+		// Nothing more is expected from the client, so reading only tells us
+		// when it has gone away.
+		_, _ = io.Copy(io.Discard, conn)
+		close(closed)
+	}()
+	for {
+		select {
+		case <-closed:
+			return
+		case <-a.done:
+			return
+		case event := <-events:
+			if _, ok := wanted[event.Type]; !ok {
+				continue
+			}
+			entry := EventEntry{
+				Type:   event.Type,
+				Time:   event.Time,
+				Fields: event.Fields,
+			}
+			if len(event.Key) > 0 {
+				entry.PublicKey = hex.EncodeToString(event.Key)
+				if addr := address.AddrForKey(event.Key); addr != nil {
+					entry.IPAddress = net.IP(addr[:]).String()
+				}
+			}
+			response, err := json.Marshal(entry)
+			if err != nil {
+				a.log.Debugln("Admin socket event encode error:", err)
+				continue
+			}
+			if err := encoder.Encode(&AdminSocketResponse{Status: "event", Response: response}); err != nil {
+				a.log.Debugln("Admin socket event write error:", err)
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package admin
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gologme/log"
+
+	"github.com/yggdrasil-network/yggdrasil-go/src/core"
+)
+
+func TestAdminSocket_Subscribe(t *testing.T) {
+	_, sk, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := core.New(sk, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Stop()
+	a := newTestAdminSocket()
+	a.core, a.done = c, make(chan struct{})
+	a.SetupAdminHandlers()
+
+	res := adminRequest(t, a, &AdminSocketRequest{
+		Name:      "subscribe",
+		Arguments: json.RawMessage(`{"types":"peer_connected,unknown"}`),
+	})
+	if res.Status != "error" {
+		t.Fatalf("expected an unknown event type to be refused, got %q", res.Status)
+	}
+
+	client, server := net.Pipe()
+	defer client.Close()
+	go a.handleRequest(server)
+	if err := json.NewEncoder(client).Encode(&AdminSocketRequest{
+		Name:      "subscribe",
+		Arguments: json.RawMessage(`{"types":"peer_connected"}`),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	decoder := json.NewDecoder(client)
+	var recv AdminSocketResponse
+	if err := decoder.Decode(&recv); err != nil {
+		t.Fatal(err)
+	}
+	if recv.Status != "success" {
+		t.Fatalf("subscribe failed: %s", recv.Error)
+	}
+
+	peer, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.PublishEvent(core.Event{Type: core.EventSessionCreated, Key: peer})
+	c.PublishEvent(core.Event{
+		Type:   core.EventPeerConnected,
+		Key:    peer,
+		Fields: map[string]string{"remote": "tls://192.0.2.1:443"},
+	})
+	_ = client.SetReadDeadline(time.Now().Add(time.Second * 5))
+	if err := decoder.Decode(&recv); err != nil {
+		t.Fatal(err)
+	}
+	var event EventEntry
+	if err := json.Unmarshal(recv.Response, &event); err != nil {
+		t.Fatal(err)
+	}
+	switch {
+	case recv.Status != "event":
+		t.Fatalf("expected an event, got %q", recv.Status)
+	case event.Type != core.EventPeerConnected:
+		t.Fatalf("expected only %s events, got %s", core.EventPeerConnected, event.Type)
+	case event.PublicKey != hex.EncodeToString(peer) || event.IPAddress == "":
+		t.Fatalf("event has key %q and address %q", event.PublicKey, event.IPAddress)
+	case event.Fields["remote"] != "tls://192.0.2.1:443" || event.Time.IsZero():
+		t.Fatalf("event is missing details: %+v", event)
+	}
+}
@@ -0,0 +1,113 @@
+package admin
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTestCertificate writes a self-signed certificate for localhost, which
+// can be used for both ends of a connection and as its own CA.
+func writeTestCertificate(t *testing.T, dir string) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err = os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+func TestAdminSocket_TLSClientCertificate(t *testing.T) {
+	certFile, keyFile := writeTestCertificate(t, t.TempDir())
+	a := newTestAdminSocket(TLSCertificate(certFile), TLSKey(keyFile), TLSClientCA(certFile))
+	config, err := a.tlsConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go a.handleRequest(conn)
+		}
+	}()
+
+	pem, err := os.ReadFile(certFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(pem)
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := func(certs []tls.Certificate) error {
+		conn, err := tls.Dial("tcp", listener.Addr().String(), &tls.Config{
+			ServerName:   "localhost",
+			RootCAs:      roots,
+			Certificates: certs,
+		})
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		if err = json.NewEncoder(conn).Encode(&AdminSocketRequest{Name: "getSelf"}); err != nil {
+			return err
+		}
+		var res AdminSocketResponse
+		return json.NewDecoder(conn).Decode(&res)
+	}
+	if err = request([]tls.Certificate{cert}); err != nil {
+		t.Fatalf("request with a client certificate failed: %s", err)
+	}
+	if err = request(nil); err == nil {
+		t.Fatal("expected a request without a client certificate to fail")
+	}
+}
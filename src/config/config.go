@@ -25,18 +25,40 @@ import (
 // options that are necessary for an Yggdrasil node to run. You will need to
 // supply one of these structs to the Yggdrasil core when starting a node.
 type NodeConfig struct {
-	Peers               []string                   `comment:"List of connection strings for outbound peer connections in URI format,\ne.g. tls://a.b.c.d:e or socks://a.b.c.d:e/f.g.h.i:j. These connections\nwill obey the operating system routing table, therefore you should\nuse this section when you may connect via different interfaces."`
-	InterfacePeers      map[string][]string        `comment:"List of connection strings for outbound peer connections in URI format,\narranged by source interface, e.g. { \"eth0\": [ \"tls://a.b.c.d:e\" ] }.\nNote that SOCKS peerings will NOT be affected by this option and should\ngo in the \"Peers\" section instead."`
-	Listen              []string                   `comment:"Listen addresses for incoming connections. You will need to add\nlisteners in order to accept incoming peerings from non-local nodes.\nMulticast peer discovery will work regardless of any listeners set\nhere. Each listener should be specified in URI format as above, e.g.\ntls://0.0.0.0:0 or tls://[::]:0 to listen on all interfaces."`
-	AdminListen         string                     `comment:"Listen address for admin connections. Default is to listen for local\nconnections either on TCP/9001 or a UNIX socket depending on your\nplatform. Use this value for yggdrasilctl -endpoint=X. To disable\nthe admin socket, use the value \"none\" instead."`
-	MulticastInterfaces []MulticastInterfaceConfig `comment:"Configuration for which interfaces multicast peer discovery should be\nenabled on. Each entry in the list should be a json object which may\ncontain Regex, Beacon, Listen, and Port. Regex is a regular expression\nwhich is matched against an interface name, and interfaces use the\nfirst configuration that they match gainst. Beacon configures whether\nor not the node should send link-local multicast beacons to advertise\ntheir presence, while listening for incoming connections on Port.\nListen controls whether or not the node listens for multicast beacons\nand opens outgoing connections."`
-	AllowedPublicKeys   []string                   `comment:"List of peer public keys to allow incoming peering connections\nfrom. If left empty/undefined then all connections will be allowed\nby default. This does not affect outgoing peerings, nor does it\naffect link-local peers discovered via multicast."`
-	PublicKey           string                     `comment:"Your public key. Your peers may ask you for this to put\ninto their AllowedPublicKeys configuration."`
-	PrivateKey          string                     `comment:"Your private key. DO NOT share this with anyone!"`
-	IfName              string                     `comment:"Local network interface name for TUN adapter, or \"auto\" to select\nan interface automatically, or \"none\" to run without TUN."`
-	IfMTU               uint64                     `comment:"Maximum Transmission Unit (MTU) size for your local TUN interface.\nDefault is the largest supported size for your platform. The lowest\npossible value is 1280."`
-	NodeInfoPrivacy     bool                       `comment:"By default, nodeinfo contains some defaults including the platform,\narchitecture and Yggdrasil version. These can help when surveying\nthe network and diagnosing network routing problems. Enabling\nnodeinfo privacy prevents this, so that only items specified in\n\"NodeInfo\" are sent back if specified."`
-	NodeInfo            map[string]interface{}     `comment:"Optional node info. This must be a { \"key\": \"value\", ... } map\nor set as null. This is entirely optional but, if set, is visible\nto the whole network on request."`
+	Peers                    []string                   `comment:"List of connection strings for outbound peer connections in URI format,\ne.g. tls://a.b.c.d:e, socks://a.b.c.d:e/f.g.h.i:j, sockstls://a.b.c.d:e/f.g.h.i:j\nor http+tls://a.b.c.d:e/f.g.h.i:j. These connections\nwill obey the operating system routing table, therefore you should\nuse this section when you may connect via different interfaces.\nAdd ?compress=true to a URI to compress the traffic on that peering,\nwhich takes effect if the remote side asks for compression as well.\nAdd ?obfs=key to a URI to encrypt and pad all of the traffic on the\npeering, including the handshake, so that it can't be recognised on the\nwire. The remote listener must be set up with the same ?obfs=key.\nA dns-srv://_yggdrasil._tcp.example.org entry looks up peers from the\nSRV records for that name, and pins their keys from any TXT records of\nthe form yggdrasil-key=<hex> on each target. Add ?scheme=tcp to change\nthe peer scheme from tls, or ?refresh=X to look up every X seconds."`
+	InterfacePeers           map[string][]string        `comment:"List of connection strings for outbound peer connections in URI format,\narranged by source interface, e.g. { \"eth0\": [ \"tls://a.b.c.d:e\" ] }.\nNote that SOCKS and HTTP proxy peerings will NOT be affected by this option\nand should go in the \"Peers\" section instead."`
+	PeerLists                []PeerListConfig           `comment:"List of signed peer lists to follow, e.g. a list of public peers. Each\nentry should be a json object which contains URL, an http or https URL\nto fetch the list from, and PublicKey, the key of the publisher. It may\nalso contain Refresh, the number of seconds between fetches (default\n3600), and CacheFile, a file to keep the last good copy in, so that it\ncan be used if the URL can't be reached. The peers in a list are added\nas if they were in Peers, and removed when they are no longer listed\nor the list has expired."`
+	PeerRetryMinInterval     uint64                     `comment:"Minimum time in seconds to wait before calling a peer from Peers or\nInterfacePeers again after the connection failed or dropped. The wait\ndoubles after each consecutive failure, up to PeerRetryMaxInterval,\nwith some randomness added so that nodes don't all reconnect at once.\nIt is reset once a connection to the peer is established."`
+	PeerRetryMaxInterval     uint64                     `comment:"Maximum time in seconds to wait before calling a peer again."`
+	HeartbeatInterval        uint64                     `comment:"How often in seconds to send a heartbeat to each connected peer. These\nare used to measure the round-trip time to the peer and to detect\nconnections that have silently stopped working."`
+	HeartbeatTimeout         uint64                     `comment:"How long in seconds to wait without hearing anything from a connected\npeer before the connection is closed."`
+	MaxUploadRate            uint64                     `comment:"Maximum rate in bytes per second to send to all peers combined, or 0\nfor no limit. Individual peerings and listeners can also be limited by\nadding ?maxup=X and ?maxdown=X to their URIs, e.g.\ntls://a.b.c.d:e?maxup=1000000"`
+	MaxDownloadRate          uint64                     `comment:"Maximum rate in bytes per second to receive from all peers combined,\nor 0 for no limit."`
+	TLSCertificateSubject    string                     `comment:"Subject of the self-signed certificate used by TLS, QUIC and WSS\nlisteners and peerings. By default this is the public key of the node,\nwhich makes it easy to recognise Yggdrasil connections. Set this to a\nhostname, or to \"random\" to pick a random one at each startup."`
+	MaxInboundPeers          uint64                     `comment:"Maximum number of inbound peerings to accept on the listeners, including\nthose still being set up, or 0 for no limit. Connections from link-local\naddresses, e.g. from multicast peers, are not counted."`
+	MaxInboundPeersPerSource uint64                     `comment:"Maximum number of inbound peerings to accept from a single IPv4 address\nor IPv6 /64, or 0 for no limit."`
+	InboundHandshakeRate     uint64                     `comment:"Maximum number of inbound connection attempts per minute to accept from\na single IPv4 address or IPv6 /64, or 0 for no limit. Connections over\nany of these limits are closed before the handshake."`
+	Listen                   []string                   `comment:"Listen addresses for incoming connections. You will need to add\nlisteners in order to accept incoming peerings from non-local nodes.\nMulticast peer discovery will work regardless of any listeners set\nhere. Each listener should be specified in URI format as above, e.g.\ntls://0.0.0.0:0 or tls://[::]:0 to listen on all interfaces."`
+	AdminListen              string                     `comment:"Listen address for admin connections. Default is to listen for local\nconnections either on TCP/9001 or a UNIX socket depending on your\nplatform. Use this value for yggdrasilctl -endpoint=X. To disable\nthe admin socket, use the value \"none\" instead. For remote access,\nuse a tls:// address together with AdminTLSCertificate and AdminTLSKey."`
+	AdminTLSCertificate      string                     `comment:"Path to a PEM file with the certificate to use when AdminListen is a\ntls:// address, e.g. tls://[::]:9001. The certificate should be valid\nfor the host name or address that yggdrasilctl connects to."`
+	AdminTLSKey              string                     `comment:"Path to a PEM file with the private key for AdminTLSCertificate."`
+	AdminTLSClientCA         string                     `comment:"Path to a PEM file with the CA certificates that admin clients must\npresent a certificate from when AdminListen is a tls:// address. If\nempty, client certificates are not checked. Use yggdrasilctl -tlscert\nand -tlskey to give a client certificate."`
+	AdminHTTPListen          string                     `comment:"Listen address for the admin HTTP gateway, e.g. http://[::1]:9002,\nor https://[::]:9002 to use AdminTLSCertificate and AdminTLSKey.\nEvery admin request can be made with POST /api/<request>, with the\narguments as a JSON object in the body and a Content-Type of\napplication/json, and requests that start with get and have no\narguments with GET as well. Requests from web pages, which have an\nOrigin header, are refused. The token, if AdminTokens\nare set, is given in an \"Authorization: Bearer <token>\" header. GET /api\nlists the requests. Leave empty to disable the gateway."`
+	AdminTokens              []AdminTokenConfig         `comment:"List of tokens that admin connections must give to be allowed to make\nrequests. Each entry should be a json object which contains Token, a\nsecret string, and Role, either \"admin\" to allow every request or\n\"read-only\" to allow only list, get* and subscribe requests, e.g. for\nmonitoring. It may also contain Commands, a list of further requests\nto allow, where * matches anything. If no tokens are set then no\nauthentication is required. Use yggdrasilctl -token=X or set YGGDRASIL_ADMIN_TOKEN."`
+	MetricsListen            string                     `comment:"Listen address for Prometheus metrics, e.g. http://[::1]:9003, which\nare then served at /metrics. These include traffic counters for peers,\ntotals for sessions, rejected handshakes, multicast beacons and TUN\npackets.\nLeave empty to disable metrics."`
+	MetricsPerSession        bool                       `comment:"Export traffic counters for each session as well as the totals. Every\nnode that traffic is exchanged with adds its own series, so only enable\nthis on nodes with few sessions, or if the metrics store can cope."`
+	MulticastInterfaces      []MulticastInterfaceConfig `comment:"Configuration for which interfaces multicast peer discovery should be\nenabled on. Each entry in the list should be a json object which may\ncontain Regex, Beacon, Listen, and Port. Regex is a regular expression\nwhich is matched against an interface name, and interfaces use the\nfirst configuration that they match gainst. Beacon configures whether\nor not the node should send link-local multicast beacons to advertise\ntheir presence, while listening for incoming connections on Port.\nListen controls whether or not the node listens for multicast beacons\nand opens outgoing connections."`
+	AllowedPublicKeys        []string                   `comment:"List of peer public keys to allow incoming peering connections\nfrom. If left empty/undefined then all connections will be allowed\nby default. This does not affect outgoing peerings, nor does it\naffect link-local peers discovered via multicast. These can also be\nchanged at runtime with yggdrasilctl addAllowedKey/removeAllowedKey."`
+	BlockedPublicKeys        []string                   `comment:"List of peer public keys to refuse peering connections with, both\nincoming and outgoing, including link-local peers discovered via\nmulticast. These can also be changed at runtime with yggdrasilctl."`
+	BlockedAddresses         []string                   `comment:"List of IP addresses or prefixes in CIDR notation, e.g. 192.0.2.0/24,\nto refuse incoming peering connections from. The connections are\nclosed before the handshake."`
+	AutoBanThreshold         uint64                     `comment:"Number of failed handshakes from a single IPv4 address or IPv6 /64\nafter which it is banned for AutoBanDuration, e.g. because it uses\nan incompatible version, the wrong password or a key that is not\nallowed. Set to 0 to disable automatic bans. Link-local peers are\nnever banned."`
+	AutoBanDuration          uint64                     `comment:"Time in seconds to ban a source for after AutoBanThreshold failures."`
+	PublicKey                string                     `comment:"Your public key. Your peers may ask you for this to put\ninto their AllowedPublicKeys configuration."`
+	PrivateKey               string                     `comment:"Your private key. DO NOT share this with anyone!"`
+	IfName                   string                     `comment:"Local network interface name for TUN adapter, or \"auto\" to select\nan interface automatically, or \"none\" to run without TUN."`
+	IfMTU                    uint64                     `comment:"Maximum Transmission Unit (MTU) size for your local TUN interface.\nDefault is the largest supported size for your platform. The lowest\npossible value is 1280."`
+	NodeInfoPrivacy          bool                       `comment:"By default, nodeinfo contains some defaults including the platform,\narchitecture and Yggdrasil version. These can help when surveying\nthe network and diagnosing network routing problems. Enabling\nnodeinfo privacy prevents this, so that only items specified in\n\"NodeInfo\" are sent back if specified."`
+	NodeInfo                 map[string]interface{}     `comment:"Optional node info. This must be a { \"key\": \"value\", ... } map\nor set as null. This is entirely optional but, if set, is visible\nto the whole network on request."`
 }
 
 type MulticastInterfaceConfig struct {
@@ -47,6 +69,19 @@ type MulticastInterfaceConfig struct {
 	Priority uint64 // really uint8, but gobind won't export it
 }
 
+type AdminTokenConfig struct {
+	Token    string
+	Role     string
+	Commands []string
+}
+
+type PeerListConfig struct {
+	URL       string
+	PublicKey string
+	Refresh   uint64
+	CacheFile string
+}
+
 // NewSigningKeys replaces the signing keypair in the NodeConfig with a new
 // signing keypair. The signing keys are used by the switch to derive the
 // structure of the spanning tree.
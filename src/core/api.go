@@ -1,6 +1,7 @@
 package core
 
 import (
+	"bytes"
 	"crypto/ed25519"
 	"encoding/json"
 	"fmt"
@@ -26,9 +27,54 @@ type PeerInfo struct {
 	Port     uint64
 	Priority uint8
 	Remote   string
+	Name     string
 	RXBytes  uint64
 	TXBytes  uint64
 	Uptime   time.Duration
+	RTT      time.Duration
+	Jitter   time.Duration
+	LastSeen time.Time
+	// Rate limits on this link in bytes per second, 0 if unlimited. These
+	// don't include the global limits, which apply to all links together.
+	MaxUpload   uint64
+	MaxDownload uint64
+	// Bytes that were delayed by a rate limit, either on this link or global.
+	ThrottledRXBytes uint64
+	ThrottledTXBytes uint64
+	// Compression statistics, if both sides agreed to compress the link.
+	Compressed        bool
+	CompressedRXBytes uint64
+	CompressedTXBytes uint64
+	CompressionRatio  float64 // uncompressed to compressed, in both directions
+}
+
+type ConfiguredPeerInfo struct {
+	URI             string
+	SourceInterface string
+	Connected       bool
+	Failures        uint
+	NextRetry       time.Time // zero if no attempt is scheduled
+	Source          string    // the peer source that added this peer, if any
+}
+
+type ListenerInfo struct {
+	URI             string
+	SourceInterface string
+	Address         string // the address that the listener is bound to
+	Peers           int    // inbound peerings accepted by the listener
+}
+
+type BlockedInfo struct {
+	PublicKeys []ed25519.PublicKey
+	Addresses  []string // IP addresses or prefixes in CIDR notation
+	Bans       []BanInfo
+}
+
+// BanInfo describes a source that has been banned automatically after
+// repeated handshake failures. The source is an IPv4 address or IPv6 /64.
+type BanInfo struct {
+	Source string
+	Until  time.Time
 }
 
 type DHTEntryInfo struct {
@@ -61,12 +107,14 @@ func (c *Core) GetSelf() SelfInfo {
 func (c *Core) GetPeers() []PeerInfo {
 	var peers []PeerInfo
 	names := make(map[net.Conn]string)
+	nodeNames := make(map[net.Conn]string)
 	phony.Block(&c.links, func() {
 		for _, info := range c.links._links {
 			if info == nil {
 				continue
 			}
 			names[info.conn] = info.lname
+			nodeNames[info.conn] = info.remoteName
 		}
 	})
 	ps := c.PacketConn.PacketConn.Debug.GetPeers()
@@ -81,16 +129,55 @@ func (c *Core) GetPeers() []PeerInfo {
 		if name := names[p.Conn]; name != "" {
 			info.Remote = name
 		}
+		info.Name = nodeNames[p.Conn]
 		if linkconn, ok := p.Conn.(*linkConn); ok {
 			info.RXBytes = atomic.LoadUint64(&linkconn.rx)
 			info.TXBytes = atomic.LoadUint64(&linkconn.tx)
 			info.Uptime = time.Since(linkconn.up)
+			info.MaxUpload = linkconn.maxUp
+			info.MaxDownload = linkconn.maxDown
+			info.ThrottledRXBytes = atomic.LoadUint64(&linkconn.throttledRX)
+			info.ThrottledTXBytes = atomic.LoadUint64(&linkconn.throttledTX)
+			if linkconn.compression != nil {
+				info.Compressed = true
+				info.CompressedRXBytes, info.CompressedTXBytes = linkconn.compression.Compressed()
+				info.CompressionRatio = linkconn.compression.Ratio()
+			}
+			if linkconn.heartbeat != nil {
+				info.RTT, info.Jitter = linkconn.heartbeat.RTT()
+				info.LastSeen = linkconn.heartbeat.LastSeen()
+			}
 		}
 		peers = append(peers, info)
 	}
 	return peers
 }
 
+// GetConfiguredPeers returns the peers from the peer list along with their
+// reconnection state. Any secrets in the URIs are redacted.
+func (c *Core) GetConfiguredPeers() []ConfiguredPeerInfo {
+	var peers []ConfiguredPeerInfo
+	phony.Block(c, func() {
+		sources := make(map[Peer]string)
+		for sourcePeer, source := range c.config._peerSources {
+			for peer := range source.peers {
+				sources[peer] = redactedURI(sourcePeer.URI)
+			}
+		}
+		for peer, state := range c.config._peers {
+			peers = append(peers, ConfiguredPeerInfo{
+				URI:             redactedURI(peer.URI),
+				SourceInterface: peer.SourceInterface,
+				Connected:       state.connected,
+				Failures:        state.failures,
+				NextRetry:       state.next,
+				Source:          sources[peer],
+			})
+		}
+	})
+	return peers
+}
+
 func (c *Core) GetDHT() []DHTEntryInfo {
 	var dhts []DHTEntryInfo
 	ds := c.PacketConn.PacketConn.Debug.GetDHT()
@@ -130,20 +217,85 @@ func (c *Core) GetSessions() []SessionInfo {
 	return sessions
 }
 
-// Listen starts a new listener (either TCP or TLS). The input should be a url.URL
-// parsed from a string of the form e.g. "tcp://a.b.c.d:e". In the case of a
-// link-local address, the interface should be provided as the second argument.
+// Listen starts a new listener (either TCP, TLS, UNIX, QUIC or WebSocket). The
+// input should be a url.URL parsed from a string of the form e.g.
+// "tcp://a.b.c.d:e". In the case of a link-local address, the interface should
+// be provided as the second argument.
 func (c *Core) Listen(u *url.URL, sintf string) (*Listener, error) {
-	switch u.Scheme {
-	case "tcp":
-		return c.links.tcp.listen(u, sintf)
-	case "tls":
-		return c.links.tls.listen(u, sintf)
-	case "unix":
-		return c.links.unix.listen(u, sintf)
-	default:
-		return nil, fmt.Errorf("unrecognised scheme %q", u.Scheme)
+	return c.links.listen(u, sintf, false)
+}
+
+// GetListeners returns the listeners that are currently running, including
+// those started by the multicast module. Any secrets in the URIs are redacted.
+func (c *Core) GetListeners() []ListenerInfo {
+	var listeners []ListenerInfo
+	phony.Block(&c.links, func() {
+		peers := make(map[string]int)
+		for _, intf := range c.links._links {
+			if intf != nil && intf.incoming {
+				peers[intf.options.listener]++
+			}
+		}
+		for listener := range c.links._listeners {
+			listeners = append(listeners, ListenerInfo{
+				URI:             redactedURI(listener.uri),
+				SourceInterface: listener.sintf,
+				Address:         listener.Addr().String(),
+				Peers:           peers[listener.uri],
+			})
+		}
+	})
+	return listeners
+}
+
+// AddListener starts a new listener from a URI, see Listen, and adds it to the
+// configured listeners. It fails if a listener from the same URI and source
+// interface is already running, even if the options are in a different order.
+func (c *Core) AddListener(uri string, sourceInterface string) error {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return err
+	}
+	if _, err = c.links.listen(u, sourceInterface, true); err != nil {
+		return err
+	}
+	if sourceInterface == "" {
+		phony.Block(c, func() {
+			c.config._listeners[ListenAddress(linkListenerURI(u))] = struct{}{}
+		})
 	}
+	return nil
+}
+
+// RemoveListener stops the listener that was started from the given URI and
+// removes it from the configured listeners. Peerings that the listener has
+// already accepted are not closed.
+func (c *Core) RemoveListener(uri string, sourceInterface string) error {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return err
+	}
+	key := linkListenerURI(u)
+	var stop []*Listener
+	phony.Block(&c.links, func() {
+		for listener := range c.links._listeners {
+			if listener.uri == key && listener.sintf == sourceInterface {
+				stop = append(stop, listener)
+			}
+		}
+	})
+	if len(stop) == 0 {
+		return fmt.Errorf("listener not running")
+	}
+	for _, listener := range stop {
+		_ = listener.Close()
+	}
+	if sourceInterface == "" {
+		phony.Block(c, func() {
+			delete(c.config._listeners, ListenAddress(key))
+		})
+	}
+	return nil
 }
 
 // Address gets the IPv6 address of the Yggdrasil node. This is always a /128
@@ -167,6 +319,20 @@ func (c *Core) Subnet() net.IPNet {
 	return net.IPNet{IP: subnet, Mask: net.CIDRMask(64, 128)}
 }
 
+// SetNodeInfo replaces the nodeinfo that is sent to remote nodes when they ask
+// for it. The node name in the nodeinfo is sent to peers in the handshake, so
+// only new peerings will see a new name.
+func (c *Core) SetNodeInfo(nodeinfo NodeInfo, privacy NodeInfoPrivacy) error {
+	if err := c.proto.nodeinfo.setNodeInfo(nodeinfo, bool(privacy)); err != nil {
+		return err
+	}
+	phony.Block(c, func() {
+		c.config._nodeinfo = nodeinfo
+		c.config._nodeinfoPrivacy = privacy
+	})
+	return nil
+}
+
 // SetLogger sets the output logger of the Yggdrasil node after startup. This
 // may be useful if you want to redirect the output later. Note that this
 // expects a Logger from the github.com/gologme/log package and not from Go's
@@ -179,50 +345,93 @@ func (c *Core) SetLogger(log Logger) {
 //
 //	tcp://a.b.c.d:e
 //	socks://a.b.c.d:e/f.g.h.i:j
+//	dns-srv://_yggdrasil._tcp.example.org
 //
 // This adds the peer to the peer list, so that they will be called again if the
-// connection drops.
+// connection drops. A dns-srv URI adds the peers that are found in DNS, and
+// keeps them up to date as the DNS records change.
 func (c *Core) AddPeer(uri string, sourceInterface string) error {
-	var known bool
-	phony.Block(c, func() {
-		_, known = c.config._peers[Peer{uri, sourceInterface}]
-	})
-	if known {
-		return fmt.Errorf("peer already configured")
-	}
 	u, err := url.Parse(uri)
 	if err != nil {
 		return err
 	}
-	info, err := c.links.call(u, sourceInterface, nil)
+	peer := Peer{uri, sourceInterface}
+	source, err := c.peerSourceFor(u)
 	if err != nil {
 		return err
 	}
+	state := &peerState{url: u}
+	var known bool
 	phony.Block(c, func() {
-		c.config._peers[Peer{uri, sourceInterface}] = &info
+		_, known = c.config._peers[peer]
+		if _, ok := c.config._peerSources[peer]; ok {
+			known = true
+		}
+		switch {
+		case known:
+		case source != nil:
+			c._addPeerSource(peer, source)
+		default:
+			c.config._peers[peer] = state
+		}
 	})
+	switch {
+	case known:
+		return fmt.Errorf("peer already configured")
+	case source != nil:
+		return nil
+	}
+	if err := c.dialPeer(peer, state); err != nil {
+		phony.Block(c, func() {
+			delete(c.config._peers, peer)
+		})
+		return err
+	}
 	return nil
 }
 
 // RemovePeer removes a peer. The peer should be specified in URI format, see AddPeer.
-// The peer is not disconnected immediately.
+// The peer is disconnected if it is connected.
 func (c *Core) RemovePeer(uri string, sourceInterface string) error {
 	var err error
 	phony.Block(c, func() {
 		peer := Peer{uri, sourceInterface}
-		linkInfo, ok := c.config._peers[peer]
-		if !ok {
+		if c._removePeerSource(peer) {
+			return
+		}
+		if !c._removePeer(peer) {
 			err = fmt.Errorf("peer not configured")
+		}
+	})
+	return err
+}
+
+// AddPeerList adds a signed peer list, which is fetched periodically. The peers
+// in it are added to the peer list, and removed again once they are no longer
+// in the list or the list expires.
+func (c *Core) AddPeerList(list PeerList) error {
+	source, err := c.newPeerListSource(list)
+	if err != nil {
+		return err
+	}
+	peer := Peer{URI: list.URL}
+	phony.Block(c, func() {
+		if _, ok := c.config._peerSources[peer]; ok {
+			err = fmt.Errorf("peer list already configured")
 			return
 		}
-		if ok && linkInfo != nil {
-			c.links.Act(nil, func() {
-				if link := c.links._links[*linkInfo]; link != nil {
-					_ = link.close()
-				}
-			})
+		c._addPeerSource(peer, source)
+	})
+	return err
+}
+
+// RemovePeerList removes a peer list along with the peers that it added.
+func (c *Core) RemovePeerList(uri string) error {
+	var err error
+	phony.Block(c, func() {
+		if !c._removePeerSource(Peer{URI: uri}) {
+			err = fmt.Errorf("peer list not configured")
 		}
-		delete(c.config._peers, peer)
 	})
 	return err
 }
@@ -240,10 +449,205 @@ func (c *Core) CallPeer(u *url.URL, sintf string) error {
 	return err
 }
 
+// AddAllowedPublicKey adds a public key to the list of keys that are allowed
+// to set up inbound peerings. If the list was empty before, then all inbound
+// peerings were allowed, so any that aren't from this key are closed.
+func (c *Core) AddAllowedPublicKey(key ed25519.PublicKey) error {
+	if len(key) != ed25519.PublicKeySize {
+		return fmt.Errorf("public key is incorrect length")
+	}
+	var pk [32]byte
+	copy(pk[:], key)
+	var err error
+	phony.Block(c, func() {
+		if _, ok := c.config._allowedPublicKeys[pk]; ok {
+			err = fmt.Errorf("public key is already allowed")
+			return
+		}
+		c.config._allowedPublicKeys[pk] = struct{}{}
+	})
+	if err != nil {
+		return err
+	}
+	c.closeDisallowedLinks()
+	return nil
+}
+
+// RemoveAllowedPublicKey removes a public key from the list of keys that are
+// allowed to set up inbound peerings, and closes any inbound peerings with it.
+// If the list is left empty, then all inbound peerings are allowed again.
+func (c *Core) RemoveAllowedPublicKey(key ed25519.PublicKey) error {
+	var pk [32]byte
+	copy(pk[:], key)
+	var err error
+	phony.Block(c, func() {
+		if _, ok := c.config._allowedPublicKeys[pk]; !ok {
+			err = fmt.Errorf("public key is not allowed")
+			return
+		}
+		delete(c.config._allowedPublicKeys, pk)
+	})
+	if err != nil {
+		return err
+	}
+	c.closeDisallowedLinks()
+	return nil
+}
+
+// GetAllowedPublicKeys returns the list of keys that are allowed to set up
+// inbound peerings. If it is empty then all inbound peerings are allowed.
+func (c *Core) GetAllowedPublicKeys() []ed25519.PublicKey {
+	var keys []ed25519.PublicKey
+	phony.Block(c, func() {
+		for pk := range c.config._allowedPublicKeys {
+			keys = append(keys, append(ed25519.PublicKey(nil), pk[:]...))
+		}
+	})
+	return keys
+}
+
+// closeDisallowedLinks closes the inbound peerings with keys that are no longer
+// allowed. Like in the handshake, link-local peerings are left alone.
+func (c *Core) closeDisallowedLinks() {
+	var allowed map[[32]byte]struct{}
+	phony.Block(c, func() {
+		allowed = make(map[[32]byte]struct{}, len(c.config._allowedPublicKeys))
+		for pk := range c.config._allowedPublicKeys {
+			allowed[pk] = struct{}{}
+		}
+	})
+	if len(allowed) == 0 {
+		return
+	}
+	phony.Block(&c.links, func() {
+		c.links._closeLinks(func(intf *link) bool {
+			var pk [32]byte
+			copy(pk[:], intf.remoteKey)
+			_, ok := allowed[pk]
+			return intf.incoming && !intf.force && !ok
+		})
+	})
+}
+
+// BlockPublicKey adds a public key to the blocklist, so that no peerings will
+// be set up with it, either inbound or outbound. Any existing peerings with the
+// key are closed.
+func (c *Core) BlockPublicKey(key ed25519.PublicKey) error {
+	if len(key) != ed25519.PublicKeySize {
+		return fmt.Errorf("public key is incorrect length")
+	}
+	var pk [32]byte
+	copy(pk[:], key)
+	phony.Block(c, func() {
+		c.config._blockedPublicKeys[pk] = struct{}{}
+	})
+	phony.Block(&c.links, func() {
+		c.links._closeLinks(func(intf *link) bool {
+			return bytes.Equal(intf.remoteKey, key)
+		})
+	})
+	return nil
+}
+
+// UnblockPublicKey removes a public key from the blocklist.
+func (c *Core) UnblockPublicKey(key ed25519.PublicKey) error {
+	var pk [32]byte
+	copy(pk[:], key)
+	var err error
+	phony.Block(c, func() {
+		if _, ok := c.config._blockedPublicKeys[pk]; !ok {
+			err = fmt.Errorf("public key is not blocked")
+			return
+		}
+		delete(c.config._blockedPublicKeys, pk)
+	})
+	return err
+}
+
+// BlockAddress adds an IP address or a prefix in CIDR notation to the
+// blocklist, so that inbound connections from it are closed before the
+// handshake. Any existing inbound peerings from it are closed.
+func (c *Core) BlockAddress(addr string) error {
+	prefix, err := parseBlockedAddress(addr)
+	if err != nil {
+		return err
+	}
+	phony.Block(c, func() {
+		c.config._blockedAddresses[prefix.String()] = prefix
+	})
+	phony.Block(&c.links, func() {
+		c.links._closeLinks(func(intf *link) bool {
+			return intf.incoming && prefix.Contains(linkIPFor(intf.conn.RemoteAddr()))
+		})
+	})
+	return nil
+}
+
+// UnblockAddress removes an IP address or prefix from the blocklist. It also
+// lifts any automatic ban on the given source.
+func (c *Core) UnblockAddress(addr string) error {
+	var found bool
+	if prefix, err := parseBlockedAddress(addr); err == nil {
+		phony.Block(c, func() {
+			if _, found = c.config._blockedAddresses[prefix.String()]; found {
+				delete(c.config._blockedAddresses, prefix.String())
+			}
+		})
+	}
+	phony.Block(&c.links, func() {
+		if c.links._isBanned(addr) {
+			found = true
+		}
+		delete(c.links._bans, addr)
+	})
+	if !found {
+		return fmt.Errorf("address is not blocked")
+	}
+	return nil
+}
+
+// GetBlocked returns the blocked public keys and addresses, along with the
+// sources that are currently banned automatically.
+func (c *Core) GetBlocked() BlockedInfo {
+	var info BlockedInfo
+	phony.Block(c, func() {
+		for pk := range c.config._blockedPublicKeys {
+			info.PublicKeys = append(info.PublicKeys, append(ed25519.PublicKey(nil), pk[:]...))
+		}
+		for addr := range c.config._blockedAddresses {
+			info.Addresses = append(info.Addresses, addr)
+		}
+	})
+	phony.Block(&c.links, func() {
+		for source, ban := range c.links._bans {
+			if c.links._isBanned(source) {
+				info.Bans = append(info.Bans, BanInfo{
+					Source: source,
+					Until:  ban.until,
+				})
+			}
+		}
+	})
+	return info
+}
+
 func (c *Core) PublicKey() ed25519.PublicKey {
 	return c.public
 }
 
+// GetHandshakeFailures returns the number of handshakes that have been
+// rejected since startup, by the reason that they were rejected for, e.g.
+// "protocol", "version", "auth" or "not-allowed".
+func (c *Core) GetHandshakeFailures() map[string]uint64 {
+	failures := make(map[string]uint64)
+	phony.Block(&c.links, func() {
+		for reason, count := range c.links._handshakeFailures {
+			failures[reason] = count
+		}
+	})
+	return failures
+}
+
 // Hack to get the admin stuff working, TODO something cleaner
 
 type AddHandler interface {
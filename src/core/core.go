@@ -25,20 +25,36 @@ type Core struct {
 	// guarantee that it will be covered by the mutex
 	phony.Inbox
 	*iwe.PacketConn
-	ctx          context.Context
-	cancel       context.CancelFunc
-	secret       ed25519.PrivateKey
-	public       ed25519.PublicKey
-	links        links
-	proto        protoHandler
-	log          Logger
-	addPeerTimer *time.Timer
-	config       struct {
-		_peers             map[Peer]*linkInfo         // configurable after startup
-		_listeners         map[ListenAddress]struct{} // configurable after startup
-		nodeinfo           NodeInfo                   // immutable after startup
-		nodeinfoPrivacy    NodeInfoPrivacy            // immutable after startup
-		_allowedPublicKeys map[[32]byte]struct{}      // configurable after startup
+	ctx      context.Context
+	cancel   context.CancelFunc
+	secret   ed25519.PrivateKey
+	public   ed25519.PublicKey
+	links    links
+	proto    protoHandler
+	events   events
+	log      Logger
+	resolver peerResolver // used to look up peers from DNS, replaced in tests
+	config   struct {
+		_peers               map[Peer]*peerState        // configurable after startup
+		_peerSources         map[Peer]*peerSource       // configurable after startup
+		_listeners           map[ListenAddress]struct{} // configurable after startup
+		_nodeinfo            NodeInfo                   // configurable after startup
+		_nodeinfoPrivacy     NodeInfoPrivacy            // configurable after startup
+		_allowedPublicKeys   map[[32]byte]struct{}      // configurable after startup
+		_blockedPublicKeys   map[[32]byte]struct{}      // configurable after startup
+		_blockedAddresses    map[string]*net.IPNet      // configurable after startup
+		peerRetryMin         time.Duration              // immutable after startup
+		peerRetryMax         time.Duration              // immutable after startup
+		heartbeatInterval    time.Duration              // immutable after startup
+		heartbeatTimeout     time.Duration              // immutable after startup
+		maxUpload            uint64                     // immutable after startup
+		maxDownload          uint64                     // immutable after startup
+		tlsSubject           string                     // immutable after startup
+		maxInbound           uint64                     // immutable after startup
+		maxInboundPerSource  uint64                     // immutable after startup
+		inboundHandshakeRate uint64                     // immutable after startup
+		autoBanThreshold     uint64                     // immutable after startup
+		autoBanDuration      time.Duration              // immutable after startup
 	}
 }
 
@@ -46,6 +62,9 @@ func New(secret ed25519.PrivateKey, logger Logger, opts ...SetupOption) (*Core,
 	c := &Core{
 		log: logger,
 	}
+	if c.log == nil {
+		c.log = log.New(io.Discard, "", 0)
+	}
 	if name := version.BuildName(); name != "unknown" {
 		c.log.Infoln("Build name:", name)
 	}
@@ -64,20 +83,33 @@ func New(secret ed25519.PrivateKey, logger Logger, opts ...SetupOption) (*Core,
 	if c.PacketConn, err = iwe.NewPacketConn(c.secret); err != nil {
 		return nil, fmt.Errorf("error creating encryption: %w", err)
 	}
-	c.config._peers = map[Peer]*linkInfo{}
+	c.resolver = net.DefaultResolver
+	c.config._peers = map[Peer]*peerState{}
+	c.config._peerSources = map[Peer]*peerSource{}
 	c.config._listeners = map[ListenAddress]struct{}{}
 	c.config._allowedPublicKeys = map[[32]byte]struct{}{}
+	c.config._blockedPublicKeys = map[[32]byte]struct{}{}
+	c.config._blockedAddresses = map[string]*net.IPNet{}
+	c.config.autoBanDuration = defaultAutoBanDuration
+	c.config.peerRetryMin = defaultPeerRetryMinInterval
+	c.config.peerRetryMax = defaultPeerRetryMaxInterval
+	c.config.heartbeatInterval = defaultHeartbeatInterval
+	c.config.heartbeatTimeout = defaultHeartbeatTimeout
 	for _, opt := range opts {
 		c._applyOption(opt)
 	}
-	if c.log == nil {
-		c.log = log.New(io.Discard, "", 0)
+	if c.config.peerRetryMax < c.config.peerRetryMin {
+		c.config.peerRetryMax = c.config.peerRetryMin
+	}
+	if c.config.heartbeatTimeout < c.config.heartbeatInterval {
+		c.config.heartbeatTimeout = c.config.heartbeatInterval
 	}
 	c.proto.init(c)
+	c.events.init(c)
 	if err := c.links.init(c); err != nil {
 		return nil, fmt.Errorf("error initialising links: %w", err)
 	}
-	if err := c.proto.nodeinfo.setNodeInfo(c.config.nodeinfo, bool(c.config.nodeinfoPrivacy)); err != nil {
+	if err := c.proto.nodeinfo.setNodeInfo(c.config._nodeinfo, bool(c.config._nodeinfoPrivacy)); err != nil {
 		return nil, fmt.Errorf("error setting node info: %w", err)
 	}
 	for listenaddr := range c.config._listeners {
@@ -86,48 +118,69 @@ func New(secret ed25519.PrivateKey, logger Logger, opts ...SetupOption) (*Core,
 			c.log.Errorf("Invalid listener URI %q specified, ignoring\n", listenaddr)
 			continue
 		}
-		if _, err = c.links.listen(u, ""); err != nil {
+		if _, err = c.links.listen(u, "", false); err != nil {
 			c.log.Errorf("Failed to start listener %q: %s\n", listenaddr, err)
 		}
 	}
-	c.Act(nil, c._addPeerLoop)
+	// If any static peers were provided in the configuration above then we
+	// should call them. From then on, the reconnect scheduler takes care of
+	// calling them again if the connection fails or drops.
+	sources := make(map[Peer]*peerSource)
+	for peer, state := range c.config._peers {
+		u, err := url.Parse(peer.URI)
+		if err != nil {
+			c.log.Errorln("Failed to parse peer url:", peer.URI, err)
+			continue
+		}
+		source, err := c.peerSourceFor(u)
+		switch {
+		case err != nil:
+			c.log.Errorln("Failed to add peer source:", peer.URI, err)
+			delete(c.config._peers, peer)
+		case source != nil:
+			delete(c.config._peers, peer)
+			sources[peer] = source
+		default:
+			state.url = u
+		}
+	}
+	for peer, state := range c.config._peers {
+		if state.url != nil {
+			go c.retryPeer(peer, state)
+		}
+	}
+	phony.Block(c, func() {
+		for peer, source := range c.config._peerSources {
+			sources[peer] = source // from PeerList options
+		}
+		for peer, source := range sources {
+			c._addPeerSource(peer, source)
+		}
+	})
 	return c, nil
 }
 
-// If any static peers were provided in the configuration above then we should
-// configure them. The loop ensures that disconnected peers will eventually
-// be reconnected with.
-func (c *Core) _addPeerLoop() {
-	select {
-	case <-c.ctx.Done():
-		return
-	default:
-	}
-	// Add peers from the Peers section
-	for peer := range c.config._peers {
-		go func(peer string, intf string) {
-			u, err := url.Parse(peer)
-			if err != nil {
-				c.log.Errorln("Failed to parse peer url:", peer, err)
+// RetryPeersNow calls all peers in the peer list that are not currently
+// connected straight away, instead of waiting for their next scheduled
+// attempt, and resets their backoff. This is useful when the network
+// conditions have changed, e.g. after switching networks.
+func (c *Core) RetryPeersNow() {
+	c.Act(nil, func() {
+		for peer, state := range c.config._peers {
+			if state.connected || state.url == nil {
+				continue
 			}
-			if err := c.CallPeer(u, intf); err != nil {
-				c.log.Errorln("Failed to add peer:", err)
+			if state.timer != nil {
+				state.timer.Stop()
+				state.timer = nil
 			}
-		}(peer.URI, peer.SourceInterface) // TODO: this should be acted and not in a goroutine?
-	}
-
-	c.addPeerTimer = time.AfterFunc(time.Minute, func() {
-		c.Act(nil, c._addPeerLoop)
+			state.failures = 0
+			state.next = time.Time{}
+			go c.retryPeer(peer, state)
+		}
 	})
 }
 
-func (c *Core) RetryPeersNow() {
-	if c.addPeerTimer != nil && !c.addPeerTimer.Stop() {
-		<-c.addPeerTimer.C
-	}
-	c.Act(nil, c._addPeerLoop)
-}
-
 // Stop shuts down the Yggdrasil node.
 func (c *Core) Stop() {
 	phony.Block(c, func() {
@@ -142,9 +195,17 @@ func (c *Core) _close() error {
 	c.cancel()
 	c.links.shutdown()
 	err := c.PacketConn.Close()
-	if c.addPeerTimer != nil {
-		c.addPeerTimer.Stop()
-		c.addPeerTimer = nil
+	for _, state := range c.config._peers {
+		if state.timer != nil {
+			state.timer.Stop()
+			state.timer = nil
+		}
+	}
+	for _, source := range c.config._peerSources {
+		if source.timer != nil {
+			source.timer.Stop()
+			source.timer = nil
+		}
 	}
 	return err
 }
@@ -0,0 +1,147 @@
+package core
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"time"
+
+	"github.com/Arceliar/phony"
+)
+
+// The types of event that can be published, see Event.
+const (
+	EventPeerConnected       = "peer_connected"       // remote, direction, type
+	EventPeerDisconnected    = "peer_disconnected"    // remote, direction, type, reason
+	EventHandshakeRejected   = "handshake_rejected"   // remote, reason, error
+	EventSessionCreated      = "session_created"      //
+	EventSessionExpired      = "session_expired"      //
+	EventRootChanged         = "root_changed"         // the new root is the key
+	EventMulticastDiscovered = "multicast_discovered" // remote, interface
+	EventConfigReloaded      = "config_reloaded"      // applied, restart_required
+)
+
+// Event is something that happened on the node, which is passed to anything
+// that has subscribed to events with Subscribe. Key is the node that the event
+// is about, if there is one, and the other details depend on the type.
+type Event struct {
+	Type   string
+	Time   time.Time
+	Key    ed25519.PublicKey
+	Fields map[string]string
+}
+
+// The number of events that can be queued for a subscriber before new events
+// are dropped, so that a slow subscriber can't hold up the node.
+const eventQueueSize = 64
+
+// The interval between checks for new and expired sessions and for changes to
+// the root, which aren't reported directly.
+const eventPollInterval = time.Second
+
+// events passes events to subscribers. Sessions and the root of the tree are
+// only watched while there are subscribers, by polling for changes.
+type events struct {
+	phony.Inbox
+	core         *Core
+	_subscribers map[chan Event]struct{}
+	_sessions    map[[32]byte]struct{} // nil if not polling
+	_root        ed25519.PublicKey
+	_timer       *time.Timer
+}
+
+func (e *events) init(c *Core) {
+	e.core = c
+	e._subscribers = make(map[chan Event]struct{})
+}
+
+// Subscribe returns a channel that receives events as they happen, and a
+// function to call to stop receiving them. Events are dropped if the channel
+// isn't read from quickly enough.
+func (c *Core) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, eventQueueSize)
+	phony.Block(&c.events, func() {
+		c.events._subscribers[ch] = struct{}{}
+		if c.events._sessions == nil {
+			c.events._startPolling()
+		}
+	})
+	unsubscribe := func() {
+		phony.Block(&c.events, func() {
+			delete(c.events._subscribers, ch)
+		})
+	}
+	return ch, unsubscribe
+}
+
+// PublishEvent passes an event to the subscribers. It is used by other modules
+// to publish their own events, e.g. EventMulticastDiscovered.
+func (c *Core) PublishEvent(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	c.events.Act(nil, func() {
+		c.events._publish(event)
+	})
+}
+
+func (e *events) _publish(event Event) {
+	for ch := range e._subscribers {
+		select {
+		case ch <- event:
+		default:
+			e.core.log.Debugf("Dropped %s event for a slow subscriber", event.Type)
+		}
+	}
+}
+
+// _startPolling records the current sessions and root, so that changes from
+// now on can be reported, and starts polling.
+func (e *events) _startPolling() {
+	e._sessions = make(map[[32]byte]struct{})
+	for _, session := range e.core.GetSessions() {
+		e._sessions[sessionKey(session.Key)] = struct{}{}
+	}
+	e._root = e.core.GetSelf().Root
+	e._schedulePoll()
+}
+
+func (e *events) _schedulePoll() {
+	e._timer = time.AfterFunc(eventPollInterval, func() {
+		e.Act(nil, e._poll)
+	})
+}
+
+// _poll publishes events for the sessions that have been created or have
+// expired, and for a change of root, since the last poll. Polling stops once
+// there are no subscribers left.
+func (e *events) _poll() {
+	if len(e._subscribers) == 0 || e.core.ctx.Err() != nil {
+		e._sessions, e._root, e._timer = nil, nil, nil
+		return
+	}
+	now := time.Now()
+	sessions := make(map[[32]byte]struct{})
+	for _, session := range e.core.GetSessions() {
+		key := sessionKey(session.Key)
+		sessions[key] = struct{}{}
+		if _, ok := e._sessions[key]; !ok {
+			e._publish(Event{Type: EventSessionCreated, Time: now, Key: session.Key})
+		}
+	}
+	for key := range e._sessions {
+		if _, ok := sessions[key]; !ok {
+			e._publish(Event{Type: EventSessionExpired, Time: now, Key: append(ed25519.PublicKey(nil), key[:]...)})
+		}
+	}
+	e._sessions = sessions
+	if root := e.core.GetSelf().Root; !bytes.Equal(root, e._root) {
+		e._root = root
+		e._publish(Event{Type: EventRootChanged, Time: now, Key: root})
+	}
+	e._schedulePoll()
+}
+
+func sessionKey(key ed25519.PublicKey) (k [32]byte) {
+	copy(k[:], key)
+	return
+}
@@ -1,7 +1,8 @@
 package core
 
 import (
-	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -15,6 +16,7 @@ import (
 
 	"github.com/Arceliar/phony"
 	"github.com/yggdrasil-network/yggdrasil-go/src/address"
+	"golang.org/x/time/rate"
 )
 
 type links struct {
@@ -24,7 +26,20 @@ type links struct {
 	tls    *linkTLS           // TLS interface support
 	unix   *linkUNIX          // UNIX interface support
 	socks  *linkSOCKS         // SOCKS interface support
+	quic   *linkQUIC          // QUIC interface support
+	ws     *linkWS            // WebSocket interface support
+	http   *linkHTTPProxy     // HTTP CONNECT proxy support
 	_links map[linkInfo]*link // *link is nil if connection in progress
+	up     *rate.Limiter      // global upload limit, nil if unlimited
+	down   *rate.Limiter      // global download limit, nil if unlimited
+	// Inbound connection limits, see link_inbound.go
+	_inbound         int                      // inbound connections, including handshakes in progress
+	_inboundSources  map[string]int           // inbound connections by source
+	_inboundAttempts map[string]*linkAttempts // handshake attempt limits by source
+	_bans            map[string]*linkBan      // handshake failures and automatic bans by source, see link_block.go
+	_listeners       map[*Listener]struct{}   // listeners of all types
+	// Rejected handshakes by reason since startup, see linkHandshakeError
+	_handshakeFailures map[string]uint64
 }
 
 // linkInfo is used as a map key
@@ -40,24 +55,34 @@ type linkDial struct {
 }
 
 type link struct {
-	lname    string
-	links    *links
-	conn     *linkConn
-	options  linkOptions
-	info     linkInfo
-	incoming bool
-	force    bool
+	lname      string
+	links      *links
+	conn       *linkConn
+	options    linkOptions
+	info       linkInfo
+	incoming   bool
+	force      bool
+	remoteName string            // node name sent by the remote side, if any
+	remoteKey  ed25519.PublicKey // set once the handshake has completed
 }
 
 type linkOptions struct {
 	pinnedEd25519Keys map[keyArray]struct{}
 	priority          uint8
 	proxyprotocol     bool
+	password          []byte
+	maxUp             uint64 // bytes per second, 0 if unlimited
+	maxDown           uint64 // bytes per second, 0 if unlimited
+	compress          bool
+	obfs              []byte // pre-shared key for linkObfs, nil if not in use
+	listener          string // URI of the listener that accepted the link, if inbound
 }
 
 type Listener struct {
 	net.Listener
 	closed chan struct{}
+	uri    string // the URI that the listener was started from
+	sintf  string
 }
 
 func (l *Listener) Close() error {
@@ -72,7 +97,17 @@ func (l *links) init(c *Core) error {
 	l.tls = l.newLinkTLS(l.tcp)
 	l.unix = l.newLinkUNIX()
 	l.socks = l.newLinkSOCKS()
+	l.quic = l.newLinkQUIC()
+	l.ws = l.newLinkWS()
+	l.http = l.newLinkHTTPProxy()
 	l._links = make(map[linkInfo]*link)
+	l._inboundSources = make(map[string]int)
+	l._inboundAttempts = make(map[string]*linkAttempts)
+	l._bans = make(map[string]*linkBan)
+	l._handshakeFailures = make(map[string]uint64)
+	l._listeners = make(map[*Listener]struct{})
+	l.up = newLinkLimiter(c.config.maxUpload)
+	l.down = newLinkLimiter(c.config.maxDownload)
 
 	var listeners []ListenAddress
 	phony.Block(c, func() {
@@ -101,6 +136,16 @@ func (l *links) shutdown() {
 			_ = l.Close()
 		}
 	})
+	phony.Block(l.quic, func() {
+		for l := range l.quic._listeners {
+			_ = l.Close()
+		}
+	})
+	phony.Block(l.ws, func() {
+		for l := range l.ws._listeners {
+			_ = l.Close()
+		}
+	})
 }
 
 func (l *links) isConnectedTo(info linkInfo) bool {
@@ -112,7 +157,7 @@ func (l *links) isConnectedTo(info linkInfo) bool {
 }
 
 func (l *links) call(u *url.URL, sintf string, errch chan<- error) (info linkInfo, err error) {
-	info = linkInfoFor(u.Scheme, sintf, u.Host)
+	info = linkInfoForURL(u, sintf)
 	if l.isConnectedTo(info) {
 		if errch != nil {
 			close(errch) // already connected, no error
@@ -144,6 +189,31 @@ func (l *links) call(u *url.URL, sintf string, errch chan<- error) (info linkInf
 		}
 		options.priority = uint8(pi)
 	}
+	if p := u.Query().Get("password"); p != "" {
+		options.password = []byte(p)
+	}
+	if p := u.Query().Get("compress"); p == "true" {
+		options.compress = true
+	}
+	if p := u.Query().Get("obfs"); p != "" {
+		options.obfs = []byte(p)
+	}
+	if p := u.Query().Get("maxup"); p != "" {
+		if options.maxUp, err = strconv.ParseUint(p, 10, 64); err != nil {
+			if errch != nil {
+				close(errch)
+			}
+			return info, fmt.Errorf("maxup invalid: %w", err)
+		}
+	}
+	if p := u.Query().Get("maxdown"); p != "" {
+		if options.maxDown, err = strconv.ParseUint(p, 10, 64); err != nil {
+			if errch != nil {
+				close(errch)
+			}
+			return info, fmt.Errorf("maxdown invalid: %w", err)
+		}
+	}
 	switch info.linkType {
 	case "tcp":
 		go func() {
@@ -158,7 +228,7 @@ func (l *links) call(u *url.URL, sintf string, errch chan<- error) (info linkInf
 			}
 		}()
 
-	case "socks":
+	case "socks", "sockstls":
 		go func() {
 			if errch != nil {
 				defer close(errch)
@@ -171,24 +241,21 @@ func (l *links) call(u *url.URL, sintf string, errch chan<- error) (info linkInf
 			}
 		}()
 
-	case "tls":
-		// SNI headers must contain hostnames and not IP addresses, so we must make sure
-		// that we do not populate the SNI with an IP literal. We do this by splitting
-		// the host-port combo from the query option and then seeing if it parses to an
-		// IP address successfully or not.
-		var tlsSNI string
-		if sni := u.Query().Get("sni"); sni != "" {
-			if net.ParseIP(sni) == nil {
-				tlsSNI = sni
+	case "http+tcp", "http+tls":
+		go func() {
+			if errch != nil {
+				defer close(errch)
 			}
-		}
-		// If the SNI is not configured still because the above failed then we'll try
-		// again but this time we'll use the host part of the peering URI instead.
-		if tlsSNI == "" {
-			if host, _, err := net.SplitHostPort(u.Host); err == nil && net.ParseIP(host) == nil {
-				tlsSNI = host
+			if err := l.http.dial(u, options); err != nil && err != io.EOF {
+				l.core.log.Warnf("Failed to dial HTTP proxy %s: %s\n", u.Host, err)
+				if errch != nil {
+					errch <- err
+				}
 			}
-		}
+		}()
+
+	case "tls":
+		tlsSNI := tlsSNIFor(u, u.Host)
 		go func() {
 			if errch != nil {
 				defer close(errch)
@@ -201,6 +268,34 @@ func (l *links) call(u *url.URL, sintf string, errch chan<- error) (info linkInf
 			}
 		}()
 
+	case "quic":
+		tlsSNI := tlsSNIFor(u, u.Host)
+		go func() {
+			if errch != nil {
+				defer close(errch)
+			}
+			if err := l.quic.dial(u, options, sintf, tlsSNI); err != nil && err != io.EOF {
+				l.core.log.Warnf("Failed to dial QUIC %s: %s\n", u.Host, err)
+				if errch != nil {
+					errch <- err
+				}
+			}
+		}()
+
+	case "ws", "wss":
+		tlsSNI := tlsSNIFor(u, u.Host)
+		go func() {
+			if errch != nil {
+				defer close(errch)
+			}
+			if err := l.ws.dial(u, options, sintf, tlsSNI); err != nil && err != io.EOF {
+				l.core.log.Warnf("Failed to dial %s %s: %s\n", strings.ToUpper(u.Scheme), u.Host, err)
+				if errch != nil {
+					errch <- err
+				}
+			}
+		}()
+
 	case "unix":
 		go func() {
 			if errch != nil {
@@ -223,7 +318,30 @@ func (l *links) call(u *url.URL, sintf string, errch chan<- error) (info linkInf
 	return info, nil
 }
 
-func (l *links) listen(u *url.URL, sintf string) (*Listener, error) {
+var errListenerRunning = errors.New("listener already running")
+
+// listen starts a listener from the given URI. If unique is set, then the
+// listener is refused with errListenerRunning if there is already one running
+// from the same URI and source interface.
+func (l *links) listen(u *url.URL, sintf string, unique bool) (*Listener, error) {
+	uri := linkListenerURI(u)
+	running := func() bool {
+		for listener := range l._listeners {
+			if listener.uri == uri && listener.sintf == sintf {
+				return true
+			}
+		}
+		return false
+	}
+	if unique {
+		var found bool
+		phony.Block(l, func() {
+			found = running()
+		})
+		if found {
+			return nil, errListenerRunning
+		}
+	}
 	var listener *Listener
 	var err error
 	switch u.Scheme {
@@ -233,18 +351,93 @@ func (l *links) listen(u *url.URL, sintf string) (*Listener, error) {
 		listener, err = l.tls.listen(u, sintf)
 	case "unix":
 		listener, err = l.unix.listen(u, sintf)
+	case "quic":
+		listener, err = l.quic.listen(u, sintf)
+	case "ws", "wss":
+		listener, err = l.ws.listen(u, sintf)
 	default:
 		return nil, fmt.Errorf("unrecognised scheme %q", u.Scheme)
 	}
-	return listener, err
+	if err != nil {
+		return nil, err
+	}
+	listener.uri, listener.sintf = uri, sintf
+	// Another listener from the same URI may have been started while this one
+	// was, so check again in the same step as adding it.
+	phony.Block(l, func() {
+		if unique && running() {
+			err = errListenerRunning
+			return
+		}
+		l._listeners[listener] = struct{}{}
+	})
+	if err != nil {
+		_ = listener.Close()
+		return nil, err
+	}
+	go func() {
+		<-listener.closed
+		l.Act(nil, func() {
+			delete(l._listeners, listener)
+		})
+	}()
+	return listener, nil
 }
 
 func (l *links) create(conn net.Conn, dial *linkDial, name string, info linkInfo, incoming, force bool, options linkOptions) error {
+	// Inbound connections are subject to the inbound limits, except for those
+	// from link-local addresses, e.g. from multicast peers. Connections over
+	// the limits are closed straight away and only logged at debug level, so
+	// that a flood of them can't fill the logs either.
+	limited := incoming && !force
+	source := linkSourceFor(conn.RemoteAddr())
+	if incoming {
+		var blocked bool
+		phony.Block(l.core, func() {
+			blocked = l.core._isBlockedAddress(linkIPFor(conn.RemoteAddr()))
+		})
+		if blocked {
+			l.core.log.Debugf("Rejected inbound connection %s from %s: address is blocked", name, conn.RemoteAddr())
+			_ = conn.Close()
+			return nil
+		}
+	}
+	if limited {
+		var err error
+		phony.Block(l, func() {
+			err = l._acceptInbound(source)
+		})
+		if err != nil {
+			l.core.log.Debugf("Rejected inbound connection %s from %s: %s", name, conn.RemoteAddr(), err)
+			_ = conn.Close()
+			return nil
+		}
+	}
+	lc := &linkConn{
+		up:      time.Now(),
+		maxUp:   options.maxUp,
+		maxDown: options.maxDown,
+	}
+	for _, limiter := range []*rate.Limiter{newLinkLimiter(options.maxUp), l.up} {
+		if limiter != nil {
+			lc.upLimits = append(lc.upLimits, limiter)
+		}
+	}
+	for _, limiter := range []*rate.Limiter{newLinkLimiter(options.maxDown), l.down} {
+		if limiter != nil {
+			lc.downLimits = append(lc.downLimits, limiter)
+		}
+	}
+	// The byte counts and rate limits apply beneath the obfuscation, and the
+	// heartbeats and compression that are added after the handshake, so that
+	// they measure what is actually sent over the wire.
+	conn = &linkWire{Conn: conn, link: lc}
+	if options.obfs != nil {
+		conn = newLinkObfs(conn, options.obfs)
+	}
+	lc.Conn = conn
 	intf := link{
-		conn: &linkConn{
-			Conn: conn,
-			up:   time.Now(),
-		},
+		conn:     lc,
 		lname:    name,
 		links:    l,
 		options:  options,
@@ -253,8 +446,30 @@ func (l *links) create(conn net.Conn, dial *linkDial, name string, info linkInfo
 		force:    force,
 	}
 	go func() {
+		if limited {
+			defer l.Act(nil, func() {
+				l._releaseInbound(source)
+			})
+		}
 		if err := intf.handler(dial); err != nil {
 			l.core.log.Errorf("Link handler %s error (%s): %s", name, conn.RemoteAddr(), err)
+			var rejected *linkHandshakeError
+			if errors.As(err, &rejected) {
+				l.core.PublishEvent(Event{
+					Type: EventHandshakeRejected,
+					Fields: map[string]string{
+						"remote": name,
+						"reason": rejected.reason,
+						"error":  rejected.err.Error(),
+					},
+				})
+				l.Act(nil, func() {
+					l._handshakeFailures[rejected.reason]++
+					if limited {
+						l._handshakeFailed(source, rejected)
+					}
+				})
+			}
 		}
 	}()
 	return nil
@@ -278,9 +493,29 @@ func (intf *link) handler(dial *linkDial) error {
 		delete(intf.links._links, intf.info)
 	})
 
-	meta := version_getBaseMetadata()
-	meta.key = intf.links.core.public
-	metaBytes := meta.encode()
+	// If we dialled this link then let the reconnect scheduler know when it
+	// goes away, whether or not the handshake succeeded.
+	if !intf.incoming && dial != nil {
+		defer intf.links.core.Act(nil, func() {
+			intf.links.core._peerDisconnected(dial.url)
+		})
+	}
+
+	local := version_getBaseMetadata()
+	local.key = intf.links.core.public
+	local.priority = intf.options.priority
+	if intf.options.compress {
+		local.features |= metaFeatureCompression
+	}
+	phony.Block(intf.links.core, func() {
+		if name, ok := intf.links.core.config._nodeinfo["name"].(string); ok {
+			local.name = name
+		}
+	})
+	if _, err := rand.Read(local.nonce[:]); err != nil {
+		return fmt.Errorf("failed to generate handshake nonce: %w", err)
+	}
+	metaBytes := local.encode()
 	if err := intf.conn.SetDeadline(time.Now().Add(time.Second * 6)); err != nil {
 		return fmt.Errorf("failed to set handshake deadline: %w", err)
 	}
@@ -291,58 +526,102 @@ func (intf *link) handler(dial *linkDial) error {
 	case err == nil && n != len(metaBytes):
 		return fmt.Errorf("incomplete handshake send")
 	}
-	if _, err = io.ReadFull(intf.conn, metaBytes); err != nil {
+	meta := version_metadata{}
+	remoteMetaBytes := make([]byte, version_headerLength)
+	if _, err = io.ReadFull(intf.conn, remoteMetaBytes); err != nil {
 		return fmt.Errorf("read handshake: %w", err)
 	}
-	if err = intf.conn.SetDeadline(time.Time{}); err != nil {
-		return fmt.Errorf("failed to clear handshake deadline: %w", err)
+	length, ok := meta.decodeHeader(remoteMetaBytes)
+	if !ok {
+		return &linkHandshakeError{linkRejectProtocol, errors.New("failed to decode metadata")}
+	}
+	remoteMetaBytes = append(remoteMetaBytes, make([]byte, length)...)
+	if _, err = io.ReadFull(intf.conn, remoteMetaBytes[version_headerLength:]); err != nil {
+		return fmt.Errorf("read handshake: %w", err)
 	}
-	meta = version_metadata{}
-	base := version_getBaseMetadata()
-	if !meta.decode(metaBytes) {
-		return errors.New("failed to decode metadata")
+	if !meta.decode(remoteMetaBytes) {
+		return &linkHandshakeError{linkRejectProtocol, errors.New("failed to decode metadata")}
 	}
-	if !meta.check() {
+	version, ok := local.negotiate(&meta)
+	if !ok {
 		var connectError string
 		if intf.incoming {
 			connectError = "Rejected incoming connection"
 		} else {
 			connectError = "Failed to connect"
 		}
-		intf.links.core.log.Debugf("%s: %s is incompatible version (local %s, remote %s)",
+		intf.links.core.log.Debugf("%s: %s is incompatible version (local %s-%s, remote %s-%s)",
 			connectError,
 			intf.lname,
-			fmt.Sprintf("%d.%d", base.ver, base.minorVer),
-			fmt.Sprintf("%d.%d", meta.ver, meta.minorVer),
+			local.minVersion, local.maxVersion,
+			meta.minVersion, meta.maxVersion,
 		)
-		return errors.New("remote node is incompatible version")
+		return &linkHandshakeError{linkRejectVersion, errors.New("remote node is incompatible version")}
+	}
+	// Now prove that we own our key and know the password, and check that the
+	// remote side does too. If no password is set then an empty password is
+	// used, so both sides must agree on that as well.
+	auth := version_auth(intf.links.core.secret, intf.options.password, metaBytes, remoteMetaBytes)
+	if _, err = intf.conn.Write(auth); err != nil {
+		return fmt.Errorf("write handshake auth: %w", err)
+	}
+	if _, err = io.ReadFull(intf.conn, auth); err != nil {
+		return fmt.Errorf("read handshake auth: %w", err)
+	}
+	if err = intf.conn.SetDeadline(time.Time{}); err != nil {
+		return fmt.Errorf("failed to clear handshake deadline: %w", err)
 	}
-	// Check if the remote side matches the keys we expected. This is a bit of a weak
-	// check - in future versions we really should check a signature or something like that.
+	if err = version_checkAuth(auth, meta.key, intf.options.password, remoteMetaBytes, metaBytes); err != nil {
+		return &linkHandshakeError{linkRejectAuth, err}
+	}
+	// Check if the remote side matches the keys we expected. The signature has
+	// been checked above, so we know that the remote side owns the key.
 	if pinned := intf.options.pinnedEd25519Keys; len(pinned) > 0 {
 		var key keyArray
 		copy(key[:], meta.key)
 		if _, allowed := pinned[key]; !allowed {
-			return fmt.Errorf("node public key that does not match pinned keys")
+			return &linkHandshakeError{linkRejectPinnedKey, fmt.Errorf("node public key that does not match pinned keys")}
 		}
 	}
 	// Check if we're authorized to connect to this key / IP
-	allowed := intf.links.core.config._allowedPublicKeys
-	isallowed := len(allowed) == 0
-	for k := range allowed {
-		if bytes.Equal(k[:], meta.key) {
-			isallowed = true
-			break
-		}
+	var isallowed, isblocked bool
+	phony.Block(intf.links.core, func() {
+		isallowed = intf.links.core._isAllowedPublicKey(meta.key)
+		var key [32]byte
+		copy(key[:], meta.key)
+		_, isblocked = intf.links.core.config._blockedPublicKeys[key]
+	})
+	if isblocked {
+		_ = intf.close()
+		return &linkHandshakeError{linkRejectBlocked, fmt.Errorf("node public key %q is blocked", hex.EncodeToString(meta.key))}
 	}
 	if intf.incoming && !intf.force && !isallowed {
 		_ = intf.close()
-		return fmt.Errorf("node public key %q is not in AllowedPublicKeys", hex.EncodeToString(meta.key))
+		return &linkHandshakeError{linkRejectNotAllowed, fmt.Errorf("node public key %q is not in AllowedPublicKeys", hex.EncodeToString(meta.key))}
 	}
 
+	// Everything sent over the link from here on is framed, so that we can
+	// send heartbeats alongside the traffic.
+	heartbeat := newLinkHeartbeat(
+		intf.conn.Conn,
+		intf.links.core.config.heartbeatInterval,
+		intf.links.core.config.heartbeatTimeout,
+	)
+	intf.conn.Conn, intf.conn.heartbeat = heartbeat, heartbeat
+	if local.features&meta.features&metaFeatureCompression != 0 {
+		compression := newLinkCompression(intf.conn.Conn)
+		intf.conn.Conn, intf.conn.compression = compression, compression
+	}
+
+	intf.remoteName, intf.remoteKey = meta.name, meta.key
 	phony.Block(intf.links, func() {
 		intf.links._links[intf.info] = intf
 	})
+	if !intf.incoming && dial != nil {
+		intf.links.core.Act(nil, func() {
+			intf.links.core._peerConnected(dial.url)
+		})
+	}
 
 	dir := "outbound"
 	if intf.incoming {
@@ -353,8 +632,26 @@ func (intf *link) handler(dial *linkDial) error {
 	localStr := intf.conn.LocalAddr()
 	intf.links.core.log.Infof("Connected %s %s: %s, source %s",
 		dir, strings.ToUpper(intf.info.linkType), remoteStr, localStr)
+	intf.links.core.PublishEvent(Event{
+		Type: EventPeerConnected,
+		Key:  meta.key,
+		Fields: map[string]string{
+			"remote":    intf.lname,
+			"direction": dir,
+			"type":      intf.info.linkType,
+		},
+	})
+
+	intf.links.core.log.Debugf("Using protocol version %s with %s", version, remoteStr)
 
-	err = intf.links.core.HandleConn(meta.key, intf.conn, intf.options.priority)
+	// Both sides use the higher (i.e. less preferred) of the two priorities,
+	// so that they agree on how the link should be used.
+	priority := intf.options.priority
+	if meta.priority > priority {
+		priority = meta.priority
+	}
+	err = intf.links.core.HandleConn(meta.key, intf.conn, priority)
+	reason := "closed"
 	switch err {
 	case io.EOF, net.ErrClosed, nil:
 		intf.links.core.log.Infof("Disconnected %s %s: %s, source %s",
@@ -362,30 +659,18 @@ func (intf *link) handler(dial *linkDial) error {
 	default:
 		intf.links.core.log.Infof("Disconnected %s %s: %s, source %s; error: %s",
 			dir, strings.ToUpper(intf.info.linkType), remoteStr, localStr, err)
+		reason = err.Error()
 	}
-
-	if !intf.incoming && dial != nil {
-		// The connection was one that we dialled, so wait a second and try to
-		// dial it again.
-		var retry func(attempt int)
-		retry = func(attempt int) {
-			// intf.links.core.log.Infof("Retrying %s (attempt %d of 5)...", dial.url.String(), attempt)
-			errch := make(chan error, 1)
-			if _, err := intf.links.call(dial.url, dial.sintf, errch); err != nil {
-				return
-			}
-			if err := <-errch; err != nil {
-				if attempt < 3 {
-					time.AfterFunc(time.Second, func() {
-						retry(attempt + 1)
-					})
-				}
-			}
-		}
-		time.AfterFunc(time.Second, func() {
-			retry(1)
-		})
-	}
+	intf.links.core.PublishEvent(Event{
+		Type: EventPeerDisconnected,
+		Key:  meta.key,
+		Fields: map[string]string{
+			"remote":    intf.lname,
+			"direction": dir,
+			"type":      intf.info.linkType,
+			"reason":    reason,
+		},
+	})
 
 	return nil
 }
@@ -402,28 +687,141 @@ func linkInfoFor(linkType, sintf, remote string) linkInfo {
 	}
 }
 
+// linkInfoForURL returns the info of an outbound link to a peer URI. It must
+// match the info that the link is created with, as it is used to check whether
+// we are already connected before calling, and to find the link again when
+// the peer is removed.
+func linkInfoForURL(u *url.URL, sintf string) linkInfo {
+	switch u.Scheme {
+	case "ws", "wss":
+		// Several WebSocket peers may share a host, on different paths.
+		return linkInfoFor(u.Scheme, sintf, u.Host+u.Path)
+	case "socks", "sockstls", "http+tcp", "http+tls":
+		// The same target may be reachable through more than one proxy, e.g.
+		// when using several Tor daemons, so the link identity must include
+		// both. Proxied links have no source interface.
+		target := strings.Split(strings.Trim(u.Path, "/"), "/")[0]
+		return linkInfoFor(u.Scheme, "", u.Host+"/"+target)
+	}
+	return linkInfoFor(u.Scheme, sintf, u.Host)
+}
+
+// The peering options that carry secrets, see redactedURI.
+var linkSecretOptions = map[string]struct{}{
+	"password": {},
+	"obfs":     {},
+}
+
+// redactedURI returns a peer or listener URI with the password in the user
+// info and the values of the peering options that carry secrets replaced by
+// "xxxxx", in the same way as url.URL.Redacted. The API returns URIs in this
+// form, as they can be read by anyone who can call the read-only admin calls.
+func redactedURI(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return strings.SplitN(uri, "?", 2)[0]
+	}
+	options := strings.Split(u.RawQuery, "&")
+	for i, option := range options {
+		raw, _, _ := strings.Cut(option, "=")
+		name, err := url.QueryUnescape(raw)
+		if err != nil {
+			continue
+		}
+		if _, ok := linkSecretOptions[name]; ok {
+			options[i] = raw + "=xxxxx"
+		}
+	}
+	u.RawQuery = strings.Join(options, "&")
+	return u.Redacted()
+}
+
+// linkNameForURL returns the name of an outbound link to a peer URI, which is
+// used in logs and shown to users. It doesn't include any credentials or the
+// peering options, which may contain a password.
+func linkNameForURL(u *url.URL) string {
+	return strings.TrimRight(strings.SplitN(u.Redacted(), "?", 2)[0], "/")
+}
+
 type linkConn struct {
 	// tx and rx are at the beginning of the struct to ensure 64-bit alignment
 	// on 32-bit platforms, see https://pkg.go.dev/sync/atomic#pkg-note-BUG
-	rx uint64
-	tx uint64
-	up time.Time
+	rx          uint64
+	tx          uint64
+	throttledRX uint64 // bytes received that had to wait for a rate limit
+	throttledTX uint64 // bytes sent that had to wait for a rate limit
+	up          time.Time
+	maxUp       uint64           // per-link upload limit in bytes per second, 0 if none
+	maxDown     uint64           // per-link download limit in bytes per second, 0 if none
+	upLimits    []*rate.Limiter  // per-link and global upload limits, if any
+	downLimits  []*rate.Limiter  // per-link and global download limits, if any
+	heartbeat   *linkHeartbeat   // set once the handshake has completed
+	compression *linkCompression // set once the handshake has completed, if in use
 	net.Conn
 }
 
-func (c *linkConn) Read(p []byte) (n int, err error) {
-	n, err = c.Conn.Read(p)
+// linkWire sits directly on top of the underlying connection, counting the
+// bytes for a linkConn and applying its rate limits.
+type linkWire struct {
+	net.Conn
+	link *linkConn
+}
+
+func (w *linkWire) Read(p []byte) (n int, err error) {
+	c := w.link
+	if len(c.downLimits) == 0 {
+		n, err = w.Conn.Read(p)
+		atomic.AddUint64(&c.rx, uint64(n))
+		return
+	}
+	if len(p) > linkLimitChunk {
+		p = p[:linkLimitChunk]
+	}
+	n, err = w.Conn.Read(p)
 	atomic.AddUint64(&c.rx, uint64(n))
+	// Waiting after the read holds up the next one, which in turn makes the
+	// remote side slow down once the buffers along the way fill up.
+	if n > 0 && linkThrottle(n, c.downLimits...) {
+		atomic.AddUint64(&c.throttledRX, uint64(n))
+	}
 	return
 }
 
-func (c *linkConn) Write(p []byte) (n int, err error) {
-	n, err = c.Conn.Write(p)
-	atomic.AddUint64(&c.tx, uint64(n))
+func (w *linkWire) Write(p []byte) (n int, err error) {
+	c := w.link
+	if len(c.upLimits) == 0 {
+		n, err = w.Conn.Write(p)
+		atomic.AddUint64(&c.tx, uint64(n))
+		return
+	}
+	for len(p) > 0 && err == nil {
+		chunk := p
+		if len(chunk) > linkLimitChunk {
+			chunk = chunk[:linkLimitChunk]
+		}
+		if linkThrottle(len(chunk), c.upLimits...) {
+			atomic.AddUint64(&c.throttledTX, uint64(len(chunk)))
+		}
+		var written int
+		written, err = w.Conn.Write(chunk)
+		atomic.AddUint64(&c.tx, uint64(written))
+		n += written
+		p = p[written:]
+	}
 	return
 }
 
+// linkListenerURI returns the form of a listener URI that listeners are known
+// by, with the options sorted, so that two spellings of the same URI are
+// treated as the same listener.
+func linkListenerURI(u *url.URL) string {
+	normalised := *u
+	normalised.RawQuery = u.Query().Encode()
+	return normalised.String()
+}
+
 func linkOptionsForListener(u *url.URL) (l linkOptions) {
+	l.listener = linkListenerURI(u)
 	if p := u.Query().Get("priority"); p != "" {
 		if pi, err := strconv.ParseUint(p, 10, 8); err == nil {
 			l.priority = uint8(pi)
@@ -432,5 +830,24 @@ func linkOptionsForListener(u *url.URL) (l linkOptions) {
 	if p := u.Query().Get("proxyprotocol"); p == "true" {
 		l.proxyprotocol = true
 	}
+	if p := u.Query().Get("compress"); p == "true" {
+		l.compress = true
+	}
+	if p := u.Query().Get("maxup"); p != "" {
+		if limit, err := strconv.ParseUint(p, 10, 64); err == nil {
+			l.maxUp = limit
+		}
+	}
+	if p := u.Query().Get("maxdown"); p != "" {
+		if limit, err := strconv.ParseUint(p, 10, 64); err == nil {
+			l.maxDown = limit
+		}
+	}
+	if p := u.Query().Get("password"); p != "" {
+		l.password = []byte(p)
+	}
+	if p := u.Query().Get("obfs"); p != "" {
+		l.obfs = []byte(p)
+	}
 	return
 }
@@ -0,0 +1,189 @@
+package core
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// Reasons that a handshake can be rejected, used in linkHandshakeError.
+const (
+	linkRejectProtocol   = "protocol"    // metadata couldn't be decoded
+	linkRejectVersion    = "version"     // protocol version doesn't match
+	linkRejectAuth       = "auth"        // bad signature or password
+	linkRejectPinnedKey  = "pinned-key"  // key doesn't match the pinned keys
+	linkRejectNotAllowed = "not-allowed" // key isn't in AllowedPublicKeys
+	linkRejectBlocked    = "blocked"     // key is in BlockedPublicKeys
+)
+
+// linkHandshakeError is returned by the link handler when the remote side got
+// far enough through the handshake to be rejected, as opposed to the
+// connection failing. Only these count towards an automatic ban.
+type linkHandshakeError struct {
+	reason string
+	err    error
+}
+
+func (e *linkHandshakeError) Error() string {
+	return e.err.Error()
+}
+
+func (e *linkHandshakeError) Unwrap() error {
+	return e.err
+}
+
+// The default time to ban a source for, used if it is not set with the
+// AutoBanDuration option.
+const defaultAutoBanDuration = time.Minute * 10
+
+var errInboundBanned = errors.New("source is temporarily banned after repeated handshake failures")
+
+// linkBan tracks the handshake failures from a source, for the automatic bans.
+type linkBan struct {
+	failures uint      // handshake failures since the last ban
+	last     time.Time // time of the most recent failure
+	until    time.Time // banned until this time, zero if never banned
+}
+
+// linkIPFor returns the IP address that a connection came from, or nil if it
+// didn't come from an IP address, e.g. over a UNIX socket.
+func linkIPFor(addr net.Addr) net.IP {
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		return a.IP
+	case *net.UDPAddr:
+		return a.IP
+	case nil:
+		return nil
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return nil
+	}
+	return net.ParseIP(host)
+}
+
+// parseBlockedAddress parses an entry from BlockedAddresses, which is either
+// a single IP address or a prefix in CIDR notation.
+func parseBlockedAddress(addr string) (*net.IPNet, error) {
+	if _, prefix, err := net.ParseCIDR(addr); err == nil {
+		return prefix, nil
+	}
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return nil, fmt.Errorf("%q is not an IP address or prefix", addr)
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}, nil
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+}
+
+// _isBlockedAddress returns true if the IP address matches an entry in the
+// blocked addresses.
+func (c *Core) _isBlockedAddress(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, prefix := range c.config._blockedAddresses {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// _isAllowedPublicKey returns true if the key is allowed to set up inbound
+// peerings, which is the case for all keys if AllowedPublicKeys is empty.
+func (c *Core) _isAllowedPublicKey(key ed25519.PublicKey) bool {
+	if len(c.config._allowedPublicKeys) == 0 {
+		return true
+	}
+	var pk [32]byte
+	copy(pk[:], key)
+	_, ok := c.config._allowedPublicKeys[pk]
+	return ok
+}
+
+// _isBanned returns true if the source is currently banned automatically.
+func (l *links) _isBanned(source string) bool {
+	ban := l._bans[source]
+	return ban != nil && time.Now().Before(ban.until)
+}
+
+// _handshakeFailed records a rejected handshake from a source, and bans the
+// source for a while once it has failed too many times in a row. Failures
+// are forgotten once a source hasn't failed for as long as a ban lasts.
+func (l *links) _handshakeFailed(source string, err *linkHandshakeError) {
+	cfg := &l.core.config
+	if source == "" || cfg.autoBanThreshold == 0 {
+		return
+	}
+	now := time.Now()
+	ban := l._bans[source]
+	if ban == nil {
+		if len(l._bans) >= linkInboundMaxTracked {
+			l._pruneBans(now)
+		}
+		ban = &linkBan{}
+		l._bans[source] = ban
+	}
+	if now.Sub(ban.last) > cfg.autoBanDuration {
+		ban.failures = 0
+	}
+	ban.failures++
+	ban.last = now
+	if uint64(ban.failures) >= cfg.autoBanThreshold {
+		l.core.log.Warnf("Banning %s for %s after %d failed handshakes, last: %s",
+			source, cfg.autoBanDuration, ban.failures, err)
+		ban.failures = 0
+		ban.until = now.Add(cfg.autoBanDuration)
+	}
+}
+
+// _pruneBans forgets the sources that aren't banned and haven't failed for
+// long enough that their failures would be forgotten anyway. If that doesn't
+// make room for another source, then the source that failed least recently is
+// forgotten as well, preferring those that aren't banned, so that the number
+// of sources tracked never goes over linkInboundMaxTracked.
+func (l *links) _pruneBans(now time.Time) {
+	for source, ban := range l._bans {
+		if now.After(ban.until) && now.Sub(ban.last) > l.core.config.autoBanDuration {
+			delete(l._bans, source)
+		}
+	}
+	if len(l._bans) < linkInboundMaxTracked {
+		return
+	}
+	var oldest string
+	for source, ban := range l._bans {
+		if oldest == "" {
+			oldest = source
+			continue
+		}
+		current := l._bans[oldest]
+		banned, currentBanned := now.Before(ban.until), now.Before(current.until)
+		switch {
+		case banned != currentBanned:
+			if !banned {
+				oldest = source
+			}
+		case banned && ban.until.Before(current.until):
+			oldest = source
+		case !banned && ban.last.Before(current.last):
+			oldest = source
+		}
+	}
+	delete(l._bans, oldest)
+}
+
+// _closeLinks closes all of the established links that match.
+func (l *links) _closeLinks(match func(*link) bool) {
+	for _, intf := range l._links {
+		if intf != nil && match(intf) {
+			_ = intf.close()
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package core
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Arceliar/phony"
+)
+
+// TestCore_New_NilLogger checks that options which log a warning don't need a
+// logger to be given.
+func TestCore_New_NilLogger(t *testing.T) {
+	_, sk, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := New(sk, nil, BlockedAddress("bad"), PeerList{URL: "ftp://bad"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Stop()
+}
+
+// TestLinks_BansLimit fails handshakes from more sources than can be tracked,
+// and checks that the sources that are banned are kept.
+func TestLinks_BansLimit(t *testing.T) {
+	c := newTestNode(t, AutoBanThreshold(2))
+	source := func(i int) string {
+		return fmt.Sprintf("2001:db8:0:%x::/64", i)
+	}
+	rejected := &linkHandshakeError{linkRejectAuth, errPasswordMismatch}
+	phony.Block(&c.links, func() {
+		// The first source is banned, the rest only fail once each.
+		c.links._handshakeFailed(source(0), rejected)
+		c.links._handshakeFailed(source(0), rejected)
+		for i := 1; i < linkInboundMaxTracked*2; i++ {
+			c.links._handshakeFailed(source(i), rejected)
+			if len(c.links._bans) > linkInboundMaxTracked {
+				t.Fatalf("tracking %d sources", len(c.links._bans))
+			}
+		}
+		if !c.links._isBanned(source(0)) {
+			t.Fatal("the banned source was forgotten")
+		}
+		if _, ok := c.links._bans[source(1)]; ok {
+			t.Fatal("the oldest source that isn't banned is still tracked")
+		}
+	})
+}
+
+// TestLink_ProtocolRejected sends metadata that can't be decoded, which must
+// count as a rejected handshake.
+func TestLink_ProtocolRejected(t *testing.T) {
+	c := newTestNode(t)
+	addr := listenTestNode(t, c, "tcp://127.0.0.1:0")
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	// This is what a node running 0.4 sends: the old metadata with no length.
+	if _, err = conn.Write(append([]byte{'m', 'e', 't', 'a', 0, 4}, make([]byte, ed25519.PublicKeySize)...)); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 50; i++ {
+		if c.GetHandshakeFailures()[linkRejectProtocol] > 0 {
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	t.Fatal("undecodable metadata was not counted as a rejected handshake")
+}
+
+// TestCore_AddAllowedPublicKey checks that allowing the first key closes the
+// inbound peerings from any other key, since they were only allowed because
+// the list was empty.
+func TestCore_AddAllowedPublicKey(t *testing.T) {
+	nodeA, nodeB, nodeC := newTestNode(t), newTestNode(t), newTestNode(t)
+	addr := listenTestNode(t, nodeA, "tcp://127.0.0.1:0")
+	callTestNode(t, nodeB, "tcp://"+addr)
+	callTestNode(t, nodeC, "tcp://"+addr)
+	if !waitForPeers(nodeA, 2) {
+		t.Fatal("nodes did not peer")
+	}
+
+	if err := nodeA.AddAllowedPublicKey(nodeB.PublicKey()); err != nil {
+		t.Fatal(err)
+	}
+	if !waitForPeers(nodeA, 1) || !waitForPeers(nodeC, 0) {
+		t.Fatal("the peering from the key that isn't allowed was not closed")
+	}
+	if peer := nodeA.GetPeers()[0]; !peer.Key.Equal(nodeB.PublicKey()) {
+		t.Fatalf("expected the remaining peering to be with %x, got %x", nodeB.PublicKey(), peer.Key)
+	}
+	if err := nodeA.AddAllowedPublicKey(nodeB.PublicKey()); err == nil {
+		t.Fatal("allowing the same key twice did not fail")
+	}
+}
@@ -0,0 +1,92 @@
+package core
+
+import (
+	"compress/flate"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+)
+
+// linkCompression compresses the traffic on a link with DEFLATE. The stream is
+// flushed after every write, so that packets are never held back waiting for
+// more data, while still letting later packets refer back to earlier ones.
+// It is only used if both sides ask for it in the handshake.
+type linkCompression struct {
+	// rx and tx are at the beginning of the struct to ensure 64-bit alignment
+	// on 32-bit platforms, see https://pkg.go.dev/sync/atomic#pkg-note-BUG
+	rx      uint64 // compressed bytes received
+	tx      uint64 // compressed bytes sent
+	plainRX uint64 // uncompressed bytes received
+	plainTX uint64 // uncompressed bytes sent
+	reader  io.ReadCloser
+	wmutex  sync.Mutex // protects writer
+	writer  *flate.Writer
+	net.Conn
+}
+
+// linkCompressionCounter counts the compressed bytes passing through it.
+type linkCompressionCounter struct {
+	net.Conn
+	rx *uint64
+	tx *uint64
+}
+
+func (c *linkCompressionCounter) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	atomic.AddUint64(c.rx, uint64(n))
+	return n, err
+}
+
+func (c *linkCompressionCounter) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	atomic.AddUint64(c.tx, uint64(n))
+	return n, err
+}
+
+func newLinkCompression(conn net.Conn) *linkCompression {
+	c := &linkCompression{
+		Conn: conn,
+	}
+	counter := &linkCompressionCounter{
+		Conn: conn,
+		rx:   &c.rx,
+		tx:   &c.tx,
+	}
+	c.reader = flate.NewReader(counter)
+	c.writer, _ = flate.NewWriter(counter, flate.BestSpeed) // only fails on a bad level
+	return c
+}
+
+func (c *linkCompression) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	atomic.AddUint64(&c.plainRX, uint64(n))
+	return n, err
+}
+
+func (c *linkCompression) Write(p []byte) (int, error) {
+	c.wmutex.Lock()
+	defer c.wmutex.Unlock()
+	n, err := c.writer.Write(p)
+	atomic.AddUint64(&c.plainTX, uint64(n))
+	if err != nil {
+		return n, err
+	}
+	return n, c.writer.Flush()
+}
+
+// Ratio returns the ratio of uncompressed to compressed bytes that have passed
+// over the link in both directions, or zero if nothing has been sent yet.
+func (c *linkCompression) Ratio() float64 {
+	plain := atomic.LoadUint64(&c.plainRX) + atomic.LoadUint64(&c.plainTX)
+	compressed := atomic.LoadUint64(&c.rx) + atomic.LoadUint64(&c.tx)
+	if compressed == 0 {
+		return 0
+	}
+	return float64(plain) / float64(compressed)
+}
+
+// Compressed returns the number of compressed bytes received and sent.
+func (c *linkCompression) Compressed() (rx, tx uint64) {
+	return atomic.LoadUint64(&c.rx), atomic.LoadUint64(&c.tx)
+}
@@ -0,0 +1,74 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+)
+
+func TestLinkCompression_RoundTrip(t *testing.T) {
+	connA, connB := net.Pipe()
+	a, b := newLinkCompression(connA), newLinkCompression(connB)
+	defer a.Close()
+	defer b.Close()
+
+	messages := [][]byte{
+		[]byte("hello"),
+		bytes.Repeat([]byte("yggdrasil "), 1000),
+		{},
+		bytes.Repeat([]byte{0}, 65536),
+	}
+	go func() {
+		for _, msg := range messages {
+			if _, err := a.Write(msg); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	for _, msg := range messages {
+		received := make([]byte, len(msg))
+		if _, err := io.ReadFull(b, received); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(received, msg) {
+			t.Fatalf("received %d bytes that don't match what was sent", len(received))
+		}
+	}
+
+	if ratio := b.Ratio(); ratio <= 10 {
+		t.Fatalf("expected repetitive data to compress well, got a ratio of %f", ratio)
+	}
+	if rx, _ := b.Compressed(); rx == 0 || rx > 1024 {
+		t.Fatalf("received %d compressed bytes", rx)
+	}
+}
+
+// TestLink_CompressionNegotiation checks that compression is only used when
+// both sides ask for it.
+func TestLink_CompressionNegotiation(t *testing.T) {
+	for _, test := range []struct {
+		listen, call, expected bool
+	}{
+		{false, false, false},
+		{true, false, false},
+		{false, true, false},
+		{true, true, true},
+	} {
+		t.Run(fmt.Sprintf("listen=%t,call=%t", test.listen, test.call), func(t *testing.T) {
+			nodeA, nodeB := newTestNode(t), newTestNode(t)
+			addr := listenTestNode(t, nodeA, fmt.Sprintf("tcp://127.0.0.1:0?compress=%t", test.listen))
+			callTestNode(t, nodeB, fmt.Sprintf("tcp://%s?compress=%t", addr, test.call))
+			if !waitForPeers(nodeA, 1) || !waitForPeers(nodeB, 1) {
+				t.Fatal("nodes did not peer")
+			}
+			for _, peer := range append(nodeA.GetPeers(), nodeB.GetPeers()...) {
+				if peer.Compressed != test.expected {
+					t.Fatalf("expected compression=%t, got %t", test.expected, peer.Compressed)
+				}
+			}
+		})
+	}
+}
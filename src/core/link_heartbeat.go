@@ -0,0 +1,235 @@
+package core
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// The default heartbeat settings, used if they are not set with the
+// HeartbeatInterval and HeartbeatTimeout options.
+const (
+	defaultHeartbeatInterval = time.Second * 15
+	defaultHeartbeatTimeout  = time.Minute
+)
+
+var errLinkTimeout = errors.New("nothing received from peer within heartbeat timeout")
+
+// Link frame types. Once the handshake has completed, everything sent over a
+// link is wrapped in frames, so that heartbeats can be sent alongside the
+// traffic from the router.
+const (
+	linkFrameData = iota // followed by a uvarint length and that many bytes
+	linkFramePing        // followed by an 8 byte timestamp
+	linkFramePong        // followed by the 8 byte timestamp from the ping
+)
+
+// linkHeartbeat frames the traffic on a link and sends regular pings to the
+// remote side, which are answered with pongs. This is used to measure the
+// round-trip time and to detect links that have gone silent, e.g. because a
+// NAT on the path has lost its state, so that they can be closed instead of
+// sitting there forever.
+type linkHeartbeat struct {
+	net.Conn
+	reader    *bufio.Reader
+	remaining uint64     // bytes left to read from the current data frame
+	wmutex    sync.Mutex // protects wbuf and writes to the connection
+	wbuf      []byte
+	base      time.Time     // ping timestamps are relative to this
+	lastSeen  atomic.Int64  // nanoseconds since base that a frame was received
+	smutex    sync.Mutex    // protects rtt and jitter
+	rtt       time.Duration // smoothed round-trip time
+	jitter    time.Duration // round-trip time variation
+	timedOut  atomic.Bool   // set if the link was closed by the monitor
+	pongs     chan [8]byte  // timestamps from pings waiting to be answered
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func newLinkHeartbeat(conn net.Conn, interval, timeout time.Duration) *linkHeartbeat {
+	h := &linkHeartbeat{
+		Conn:   conn,
+		reader: bufio.NewReader(conn),
+		base:   time.Now(),
+		pongs:  make(chan [8]byte, 1),
+		closed: make(chan struct{}),
+	}
+	go h.monitor(interval, timeout)
+	go h.respond()
+	return h
+}
+
+func (h *linkHeartbeat) Read(p []byte) (int, error) {
+	for h.remaining == 0 {
+		if err := h.readFrameHeader(); err != nil {
+			if h.timedOut.Load() {
+				err = errLinkTimeout
+			}
+			return 0, err
+		}
+	}
+	if uint64(len(p)) > h.remaining {
+		p = p[:h.remaining]
+	}
+	n, err := h.reader.Read(p)
+	h.remaining -= uint64(n)
+	return n, err
+}
+
+// readFrameHeader reads frames until the start of a data frame, answering or
+// processing any pings and pongs along the way.
+func (h *linkHeartbeat) readFrameHeader() error {
+	frameType, err := h.reader.ReadByte()
+	if err != nil {
+		return err
+	}
+	h.lastSeen.Store(int64(time.Since(h.base)))
+	switch frameType {
+	case linkFrameData:
+		if h.remaining, err = binary.ReadUvarint(h.reader); err != nil {
+			return err
+		}
+	case linkFramePing:
+		var ts [8]byte
+		if _, err = io.ReadFull(h.reader, ts[:]); err != nil {
+			return err
+		}
+		// The pong is sent from another goroutine, as blocking here on a write
+		// could deadlock if the remote side is also blocked writing to us. If a
+		// pong is already waiting to be sent then this ping is dropped.
+		select {
+		case h.pongs <- ts:
+		default:
+		}
+	case linkFramePong:
+		var ts [8]byte
+		if _, err = io.ReadFull(h.reader, ts[:]); err != nil {
+			return err
+		}
+		sent := time.Duration(binary.BigEndian.Uint64(ts[:]))
+		h.update(time.Since(h.base) - sent)
+	default:
+		return fmt.Errorf("unexpected link frame type %d", frameType)
+	}
+	return nil
+}
+
+// update folds a new round-trip time sample into the smoothed round-trip time
+// and variation, in the same way as TCP does (RFC 6298).
+func (h *linkHeartbeat) update(sample time.Duration) {
+	if sample < 0 {
+		return
+	}
+	h.smutex.Lock()
+	defer h.smutex.Unlock()
+	if h.rtt == 0 {
+		h.rtt, h.jitter = sample, sample/2
+		return
+	}
+	diff := h.rtt - sample
+	if diff < 0 {
+		diff = -diff
+	}
+	h.jitter = (3*h.jitter + diff) / 4
+	h.rtt = (7*h.rtt + sample) / 8
+}
+
+func (h *linkHeartbeat) Write(p []byte) (int, error) {
+	h.wmutex.Lock()
+	defer h.wmutex.Unlock()
+	h.wbuf = append(h.wbuf[:0], linkFrameData)
+	h.wbuf = binary.AppendUvarint(h.wbuf, uint64(len(p)))
+	h.wbuf = append(h.wbuf, p...)
+	if _, err := h.Conn.Write(h.wbuf); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (h *linkHeartbeat) writeFrameLocked(frameType byte, payload []byte) error {
+	h.wbuf = append(h.wbuf[:0], frameType)
+	h.wbuf = append(h.wbuf, payload...)
+	_, err := h.Conn.Write(h.wbuf)
+	return err
+}
+
+// respond sends a pong for each ping that the reader has received.
+func (h *linkHeartbeat) respond() {
+	for {
+		select {
+		case <-h.closed:
+			return
+		case ts := <-h.pongs:
+			h.wmutex.Lock()
+			err := h.writeFrameLocked(linkFramePong, ts[:])
+			h.wmutex.Unlock()
+			if err != nil {
+				_ = h.Close()
+				return
+			}
+		}
+	}
+}
+
+// monitor sends a ping every interval, and closes the link if nothing has been
+// received from the remote side within the timeout.
+func (h *linkHeartbeat) monitor(interval, timeout time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-h.closed:
+			return
+		case <-ticker.C:
+		}
+		if h.LastSeen().Before(time.Now().Add(-timeout)) {
+			h.timedOut.Store(true)
+			_ = h.Close()
+			return
+		}
+		go h.ping()
+	}
+}
+
+// ping sends a ping to the remote side. If a write is already in progress then
+// there's no need to wait for it, as the traffic will do just as well to keep
+// the link alive, so we will send a ping next time around instead. This also
+// means that a write that is stuck can't hold up the monitor.
+func (h *linkHeartbeat) ping() {
+	if !h.wmutex.TryLock() {
+		return
+	}
+	defer h.wmutex.Unlock()
+	var ts [8]byte
+	binary.BigEndian.PutUint64(ts[:], uint64(time.Since(h.base)))
+	if err := h.writeFrameLocked(linkFramePing, ts[:]); err != nil {
+		_ = h.Close()
+	}
+}
+
+// RTT returns the smoothed round-trip time and its variation, or zero if no
+// pongs have been received yet.
+func (h *linkHeartbeat) RTT() (rtt, jitter time.Duration) {
+	h.smutex.Lock()
+	defer h.smutex.Unlock()
+	return h.rtt, h.jitter
+}
+
+// LastSeen returns the time that anything was last received from the remote
+// side. Before that happens, it is the time that the heartbeat started.
+func (h *linkHeartbeat) LastSeen() time.Time {
+	return h.base.Add(time.Duration(h.lastSeen.Load()))
+}
+
+func (h *linkHeartbeat) Close() error {
+	h.closeOnce.Do(func() {
+		close(h.closed)
+	})
+	return h.Conn.Close()
+}
@@ -0,0 +1,80 @@
+package core
+
+import (
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestLinkHeartbeat_RTT(t *testing.T) {
+	connA, connB := net.Pipe()
+	a := newLinkHeartbeat(connA, 20*time.Millisecond, time.Second)
+	b := newLinkHeartbeat(connB, 20*time.Millisecond, time.Second)
+	defer a.Close()
+	defer b.Close()
+
+	// Pings and pongs are only processed while reading, so both sides need a
+	// reader running. Side B echoes back what it receives.
+	go func() {
+		_, _ = io.Copy(b, b)
+	}()
+	received := make(chan []byte)
+	go func() {
+		buf := make([]byte, 64)
+		for {
+			n, err := a.Read(buf)
+			if err != nil {
+				close(received)
+				return
+			}
+			received <- append([]byte(nil), buf[:n]...)
+		}
+	}()
+
+	if _, err := a.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case data := <-received:
+		if string(data) != "hello" {
+			t.Fatalf("expected the data to be echoed, got %q", data)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("data was not echoed")
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if rtt, _ := a.RTT(); rtt > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("no round-trip time was measured")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if since := time.Since(a.LastSeen()); since > time.Second {
+		t.Fatalf("last seen %s ago", since)
+	}
+}
+
+func TestLinkHeartbeat_Timeout(t *testing.T) {
+	connA, connB := net.Pipe()
+	a := newLinkHeartbeat(connA, 20*time.Millisecond, 100*time.Millisecond)
+	defer a.Close()
+	// The remote side reads our pings but never answers anything.
+	go func() {
+		_, _ = io.Copy(io.Discard, connB)
+	}()
+
+	start := time.Now()
+	_, err := a.Read(make([]byte, 64))
+	if !errors.Is(err, errLinkTimeout) {
+		t.Fatalf("expected a heartbeat timeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond || elapsed > 2*time.Second {
+		t.Fatalf("timed out after %s", elapsed)
+	}
+}
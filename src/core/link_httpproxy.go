@@ -0,0 +1,129 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type linkHTTPProxy struct {
+	*links
+	dialer *net.Dialer
+}
+
+// linkHTTPProxyConn is a connection that has been through an HTTP CONNECT
+// handshake. Any bytes that the proxy sent after its response, which were
+// already read into the buffer while parsing the response, are returned
+// before reading from the connection itself.
+type linkHTTPProxyConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *linkHTTPProxyConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+func (l *links) newLinkHTTPProxy() *linkHTTPProxy {
+	lt := &linkHTTPProxy{
+		links: l,
+		dialer: &net.Dialer{
+			Timeout:   time.Second * 6,
+			KeepAlive: -1,
+		},
+	}
+	return lt
+}
+
+func (l *linkHTTPProxy) dial(url *url.URL, options linkOptions) error {
+	pathtokens := strings.Split(strings.Trim(url.Path, "/"), "/")
+	target := pathtokens[0]
+	if _, _, err := net.SplitHostPort(target); err != nil {
+		return fmt.Errorf("invalid target %q: %w", target, err)
+	}
+	info := linkInfoForURL(url, "")
+	if l.links.isConnectedTo(info) {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(l.core.ctx, time.Second*6)
+	defer cancel()
+	conn, err := l.dialer.DialContext(ctx, "tcp", url.Host)
+	if err != nil {
+		return err
+	}
+	if conn, err = l.connect(ctx, conn, url.User, target); err != nil {
+		return err
+	}
+	if url.Scheme == "http+tls" {
+		tlsconfig := l.tls.config.Clone()
+		tlsconfig.ServerName = tlsSNIFor(url, target)
+		tlsconn := tls.Client(conn, tlsconfig)
+		if err = tlsconn.HandshakeContext(ctx); err != nil {
+			_ = conn.Close()
+			return err
+		}
+		conn = tlsconn
+	}
+	dial := &linkDial{
+		url: url,
+	}
+	return l.handler(dial, info, conn, options, false)
+}
+
+// connect asks the proxy on the other end of conn to open a tunnel to the
+// target, authenticating with the given credentials if there are any.
+func (l *linkHTTPProxy) connect(ctx context.Context, conn net.Conn, user *url.Userinfo, target string) (net.Conn, error) {
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: target},
+		Host:   target,
+		Header: http.Header{},
+	}
+	if user != nil {
+		password, _ := user.Password()
+		auth := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+auth)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	if err := req.Write(conn); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	r := bufio.NewReader(conn)
+	res, err := http.ReadResponse(r, req)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("failed to read proxy response: %w", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		_ = res.Body.Close()
+		_ = conn.Close()
+		return nil, fmt.Errorf("proxy refused connection: %s", res.Status)
+	}
+	// A successful response has no body, the tunnel starts straight away,
+	// so we must not try to read or close the response body here.
+	_ = conn.SetDeadline(time.Time{})
+	return &linkHTTPProxyConn{conn, r}, nil
+}
+
+func (l *linkHTTPProxy) handler(dial *linkDial, info linkInfo, conn net.Conn, options linkOptions, incoming bool) error {
+	name := linkNameForURL(dial.url)
+	return l.links.create(
+		conn,     // connection
+		dial,     // connection URL
+		name,     // connection name
+		info,     // connection info
+		incoming, // not incoming
+		false,    // not forced
+		options,  // connection options
+	)
+}
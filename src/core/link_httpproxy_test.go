@@ -0,0 +1,83 @@
+package core
+
+import (
+	"encoding/base64"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// testHTTPProxy is an HTTP proxy that only supports CONNECT, and which
+// requires the given credentials.
+type testHTTPProxy struct {
+	t        *testing.T
+	user     string
+	password string
+}
+
+func (p *testHTTPProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodConnect {
+		http.Error(w, "only CONNECT is supported", http.StatusMethodNotAllowed)
+		return
+	}
+	auth := "Basic " + base64.StdEncoding.EncodeToString([]byte(p.user+":"+p.password))
+	if r.Header.Get("Proxy-Authorization") != auth {
+		w.Header().Set("Proxy-Authenticate", `Basic realm="test"`)
+		http.Error(w, "proxy authentication required", http.StatusProxyAuthRequired)
+		return
+	}
+	target, err := net.Dial("tcp", r.Host)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	conn, rw, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		p.t.Error(err)
+		return
+	}
+	_, _ = rw.WriteString("HTTP/1.1 200 Connection established\r\n\r\n")
+	_ = rw.Flush()
+	go func() {
+		_, _ = io.Copy(target, rw)
+		_ = target.Close()
+	}()
+	_, _ = io.Copy(conn, target)
+	_ = conn.Close()
+}
+
+func TestLink_HTTPProxy(t *testing.T) {
+	nodeA, nodeB := newTestNode(t), newTestNode(t)
+	addr := listenTestNode(t, nodeA, "tcp://127.0.0.1:0")
+	proxy := httptest.NewServer(&testHTTPProxy{t: t, user: "user", password: "secret"})
+	defer proxy.Close()
+	proxyHost := strings.TrimPrefix(proxy.URL, "http://")
+
+	// The proxy refuses the wrong credentials, which fails the call.
+	u, err := url.Parse("http+tcp://user:wrong@" + proxyHost + "/" + addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = nodeB.links.http.dial(u, linkOptions{}); err == nil || !strings.Contains(err.Error(), "407") {
+		t.Fatalf("expected the proxy to refuse the wrong credentials, got %v", err)
+	}
+
+	callTestNode(t, nodeB, "http+tcp://user:secret@"+proxyHost+"/"+addr)
+	if !waitForPeers(nodeA, 1) || !waitForPeers(nodeB, 1) {
+		t.Fatal("nodes did not peer through the HTTP proxy")
+	}
+	if remote := nodeB.GetPeers()[0].Remote; strings.Contains(remote, "secret") {
+		t.Fatalf("proxy password is shown in the peer name %s", remote)
+	}
+	u, err = url.Parse("http+tcp://user:secret@" + proxyHost + "/" + addr + "?priority=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !nodeB.links.isConnectedTo(linkInfoForURL(u, "")) {
+		t.Fatal("the proxied peering is not found by its URI")
+	}
+}
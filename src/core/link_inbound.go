@@ -0,0 +1,118 @@
+package core
+
+import (
+	"errors"
+	"net"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Once this many sources are being tracked for handshake rate limiting, the
+// ones that haven't connected recently are forgotten. This is a hard limit, so
+// that spreading attempts over many sources can't use up unlimited memory.
+const linkInboundMaxTracked = 1024
+
+// linkAttempts tracks the connection attempts from a source, for the handshake
+// rate limit.
+type linkAttempts struct {
+	limiter *rate.Limiter
+	last    time.Time // time of the most recent attempt
+}
+
+var (
+	errInboundLimit     = errors.New("too many inbound connections")
+	errInboundPerSource = errors.New("too many inbound connections from this source")
+	errInboundRate      = errors.New("too many connection attempts from this source")
+)
+
+// linkSourceFor returns the source that an inbound connection is counted
+// against for the per-source limits. IPv4 addresses are counted individually,
+// but IPv6 addresses are grouped by /64, since a single host can usually use
+// any address in its /64. Returns an empty string if the connection didn't
+// come from an IP address, e.g. over a UNIX socket.
+func linkSourceFor(addr net.Addr) string {
+	ip := linkIPFor(addr)
+	if ip == nil {
+		return ""
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4.String()
+	}
+	return ip.Mask(net.CIDRMask(64, 128)).String() + "/64"
+}
+
+// _acceptInbound checks whether an inbound connection from the given source
+// is within the configured limits, and if so counts it until _releaseInbound
+// is called. This happens before the handshake, so that connections over the
+// limits cost as little as possible.
+func (l *links) _acceptInbound(source string) error {
+	cfg := &l.core.config
+	if source != "" && l._isBanned(source) {
+		return errInboundBanned
+	}
+	if source != "" && cfg.inboundHandshakeRate > 0 {
+		attempts := l._inboundAttempts[source]
+		if attempts == nil {
+			if len(l._inboundAttempts) >= linkInboundMaxTracked {
+				l._pruneInboundAttempts()
+			}
+			burst := int(cfg.inboundHandshakeRate)
+			attempts = &linkAttempts{
+				limiter: rate.NewLimiter(rate.Every(time.Minute/time.Duration(burst)), burst),
+			}
+			l._inboundAttempts[source] = attempts
+		}
+		attempts.last = time.Now()
+		if !attempts.limiter.Allow() {
+			return errInboundRate
+		}
+	}
+	if cfg.maxInbound > 0 && uint64(l._inbound) >= cfg.maxInbound {
+		return errInboundLimit
+	}
+	if source != "" && cfg.maxInboundPerSource > 0 && uint64(l._inboundSources[source]) >= cfg.maxInboundPerSource {
+		return errInboundPerSource
+	}
+	l._inbound++
+	if source != "" {
+		l._inboundSources[source]++
+	}
+	return nil
+}
+
+// _releaseInbound stops counting an inbound connection that was accepted by
+// _acceptInbound.
+func (l *links) _releaseInbound(source string) {
+	l._inbound--
+	if source == "" {
+		return
+	}
+	if l._inboundSources[source] <= 1 {
+		delete(l._inboundSources, source)
+	} else {
+		l._inboundSources[source]--
+	}
+}
+
+// _pruneInboundAttempts forgets the sources whose rate limiters have filled
+// back up, since they would allow a new connection anyway. If that doesn't
+// make room for another source, then the one that was seen least recently is
+// forgotten as well, which gives it a fresh limit if it comes back.
+func (l *links) _pruneInboundAttempts() {
+	for source, attempts := range l._inboundAttempts {
+		if attempts.limiter.Tokens() >= float64(attempts.limiter.Burst()) {
+			delete(l._inboundAttempts, source)
+		}
+	}
+	if len(l._inboundAttempts) < linkInboundMaxTracked {
+		return
+	}
+	var oldest string
+	for source, attempts := range l._inboundAttempts {
+		if oldest == "" || attempts.last.Before(l._inboundAttempts[oldest].last) {
+			oldest = source
+		}
+	}
+	delete(l._inboundAttempts, oldest)
+}
@@ -0,0 +1,38 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/Arceliar/phony"
+)
+
+// TestLinks_InboundAttemptsLimit spreads connection attempts over more /64s
+// than can be tracked, and checks that the oldest are forgotten.
+func TestLinks_InboundAttemptsLimit(t *testing.T) {
+	c := newTestNode(t, InboundHandshakeRate(1))
+	source := func(i int) string {
+		return fmt.Sprintf("2001:db8:0:%x::/64", i)
+	}
+	phony.Block(&c.links, func() {
+		for i := 0; i < linkInboundMaxTracked*2; i++ {
+			if err := c.links._acceptInbound(source(i)); err != nil {
+				t.Fatalf("first attempt from %s was refused: %v", source(i), err)
+			}
+			c.links._releaseInbound(source(i))
+			if len(c.links._inboundAttempts) > linkInboundMaxTracked {
+				t.Fatalf("tracking %d sources", len(c.links._inboundAttempts))
+			}
+		}
+		// The most recent source is still limited, but the first has been
+		// forgotten.
+		last := source(linkInboundMaxTracked*2 - 1)
+		if err := c.links._acceptInbound(last); !errors.Is(err, errInboundRate) {
+			t.Fatalf("expected the second attempt from %s to be refused, got %v", last, err)
+		}
+		if _, ok := c.links._inboundAttempts[source(0)]; ok {
+			t.Fatal("the oldest source is still tracked")
+		}
+	})
+}
@@ -0,0 +1,47 @@
+package core
+
+import (
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// The largest number of bytes that linkConn will read or write at once when a
+// rate limit applies. Larger writes are split up, so that the limiters never
+// have to allow more than this in one go.
+const linkLimitChunk = 16384
+
+// newLinkLimiter returns a token bucket that allows the given number of bytes
+// per second, or nil if there is no limit. The bucket holds a tenth of a second
+// worth of traffic, but always at least one chunk.
+func newLinkLimiter(bytesPerSecond uint64) *rate.Limiter {
+	if bytesPerSecond == 0 {
+		return nil
+	}
+	burst := int(bytesPerSecond / 10)
+	if burst < linkLimitChunk {
+		burst = linkLimitChunk
+	}
+	return rate.NewLimiter(rate.Limit(bytesPerSecond), burst)
+}
+
+// linkThrottle waits until all of the given limiters allow n bytes, which must
+// be no more than linkLimitChunk. Nil limiters are ignored. Returns true if it
+// had to wait at all.
+func linkThrottle(n int, limiters ...*rate.Limiter) bool {
+	now := time.Now()
+	var delay time.Duration
+	for _, limiter := range limiters {
+		if limiter == nil {
+			continue
+		}
+		if d := limiter.ReserveN(now, n).DelayFrom(now); d > delay {
+			delay = d
+		}
+	}
+	if delay <= 0 {
+		return false
+	}
+	time.Sleep(delay)
+	return true
+}
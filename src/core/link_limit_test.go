@@ -0,0 +1,96 @@
+package core
+
+import (
+	"crypto/rand"
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// testLimitedPipe returns a linkConn for one end of a pipe, with an upload
+// limit applied in the same way as links.create, and the other end.
+func testLimitedPipe(t *testing.T, maxUp uint64) (*linkConn, net.Conn) {
+	local, remote := net.Pipe()
+	t.Cleanup(func() {
+		_ = local.Close()
+		_ = remote.Close()
+	})
+	lc := &linkConn{
+		up:       time.Now(),
+		maxUp:    maxUp,
+		upLimits: []*rate.Limiter{newLinkLimiter(maxUp)},
+	}
+	lc.Conn = &linkWire{Conn: local, link: lc}
+	return lc, remote
+}
+
+func TestLinkWire_Limit(t *testing.T) {
+	const limit, size = 100000, 116384
+	lc, remote := testLimitedPipe(t, limit)
+	go func() {
+		_, _ = io.Copy(io.Discard, remote)
+	}()
+	heartbeat := newLinkHeartbeat(lc.Conn, time.Minute, time.Hour)
+	lc.Conn = heartbeat
+
+	payload := make([]byte, size)
+	if _, err := rand.Read(payload); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if _, err := lc.Write(payload); err != nil {
+		t.Fatal(err)
+	}
+	// The first linkLimitChunk bytes are allowed straight away, the rest at
+	// the limit, so this takes a second.
+	elapsed := time.Since(start)
+	if elapsed < 800*time.Millisecond || elapsed > 3*time.Second {
+		t.Fatalf("sending %d bytes at %d bytes per second took %s", size, limit, elapsed)
+	}
+	// The framing is counted too, as it goes over the wire.
+	if tx := atomic.LoadUint64(&lc.tx); tx <= size {
+		t.Fatalf("counted %d bytes sent, expected more than %d", tx, size)
+	}
+	if atomic.LoadUint64(&lc.throttledTX) == 0 {
+		t.Fatal("nothing was counted as throttled")
+	}
+}
+
+func TestLinkWire_LimitCompressed(t *testing.T) {
+	const limit, size = 100000, 1024 * 1024
+	lc, remote := testLimitedPipe(t, limit)
+	received := make(chan int64, 1)
+	go func() {
+		plain := newLinkCompression(newLinkHeartbeat(remote, time.Minute, time.Hour))
+		n, _ := io.CopyN(io.Discard, plain, size)
+		received <- n
+		_, _ = io.Copy(io.Discard, plain)
+	}()
+	compression := newLinkCompression(newLinkHeartbeat(lc.Conn, time.Minute, time.Hour))
+	lc.Conn = compression
+
+	// A megabyte of zeroes compresses down to almost nothing, so it is sent
+	// well within the second that the limit would allow for a tenth of it.
+	start := time.Now()
+	if _, err := lc.Write(make([]byte, size)); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case n := <-received:
+		if n != size {
+			t.Fatalf("received %d bytes, expected %d", n, size)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("compressed data was not received")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("sending compressed data took %s, so the limit applied to the uncompressed bytes", elapsed)
+	}
+	if tx := atomic.LoadUint64(&lc.tx); tx == 0 || tx > size/10 {
+		t.Fatalf("counted %d bytes sent for %d bytes of zeroes", tx, size)
+	}
+}
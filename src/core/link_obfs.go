@@ -0,0 +1,205 @@
+package core
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"sync"
+)
+
+// Obfuscation settings. Each frame carries up to linkObfsMaxFrame bytes of data
+// followed by a random amount of padding, up to linkObfsMaxPadding bytes.
+const (
+	linkObfsSaltLength  = 32
+	linkObfsMaxFrame    = 16384
+	linkObfsMaxPadding  = 256
+	linkObfsHeaderLen   = 4 // uint16 data length, uint16 padding length
+	linkObfsKeyContext  = "yggdrasil obfs"
+	linkObfsNonceLength = 12
+)
+
+var errObfsDecrypt = errors.New("failed to decrypt obfuscated frame, check that both sides use the same obfs key")
+
+// linkObfs hides the traffic on a stream link, including the handshake, from
+// anyone who doesn't know the pre-shared key, so that the connection can't be
+// picked out by deep packet inspection. Each side starts by sending a random
+// salt, which is combined with the key to derive the key that it encrypts with.
+// After that, everything is sent in AES-GCM sealed frames with random padding,
+// so that there are no fixed bytes or packet sizes left on the wire to match.
+// It is enabled by adding ?obfs=key to a peer or listener URI, and the key
+// must be the same on both sides.
+type linkObfs struct {
+	net.Conn
+	psk    []byte
+	rmutex sync.Mutex // protects the read side
+	reader cipher.AEAD
+	rnonce [linkObfsNonceLength]byte
+	rbuf   []byte // decrypted data that hasn't been read yet
+	rframe []byte
+	wmutex sync.Mutex // protects the write side
+	writer cipher.AEAD
+	wnonce [linkObfsNonceLength]byte
+	wbuf   []byte
+}
+
+func newLinkObfs(conn net.Conn, psk []byte) *linkObfs {
+	return &linkObfs{
+		Conn: conn,
+		psk:  psk,
+	}
+}
+
+// linkObfsCipher derives the cipher for one direction of the link from the
+// pre-shared key and the salt sent by that side.
+func linkObfsCipher(psk, salt []byte) (cipher.AEAD, error) {
+	mac := hmac.New(sha256.New, psk)
+	_, _ = mac.Write([]byte(linkObfsKeyContext))
+	_, _ = mac.Write(salt)
+	block, err := aes.NewCipher(mac.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// linkObfsIncrement moves the nonce on to the next frame. The nonce never
+// repeats for a given key, since each key is only used for a single direction
+// of a single connection.
+func linkObfsIncrement(nonce *[linkObfsNonceLength]byte) {
+	for i := len(nonce) - 1; i >= 0; i-- {
+		nonce[i]++
+		if nonce[i] != 0 {
+			return
+		}
+	}
+}
+
+func (o *linkObfs) Read(p []byte) (int, error) {
+	o.rmutex.Lock()
+	defer o.rmutex.Unlock()
+	if o.reader == nil {
+		var salt [linkObfsSaltLength]byte
+		if _, err := io.ReadFull(o.Conn, salt[:]); err != nil {
+			return 0, err
+		}
+		reader, err := linkObfsCipher(o.psk, salt[:])
+		if err != nil {
+			return 0, err
+		}
+		o.reader = reader
+	}
+	for len(o.rbuf) == 0 {
+		if err := o.readFrame(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, o.rbuf)
+	o.rbuf = o.rbuf[n:]
+	return n, nil
+}
+
+// readFrame reads and decrypts the next frame, leaving its data in rbuf.
+func (o *linkObfs) readFrame() error {
+	overhead := o.reader.Overhead()
+	header := make([]byte, linkObfsHeaderLen+overhead)
+	if _, err := io.ReadFull(o.Conn, header); err != nil {
+		return err
+	}
+	header, err := o.reader.Open(header[:0], o.rnonce[:], header, nil)
+	if err != nil {
+		return errObfsDecrypt
+	}
+	linkObfsIncrement(&o.rnonce)
+	length := int(binary.BigEndian.Uint16(header[0:2]))
+	padding := int(binary.BigEndian.Uint16(header[2:4]))
+	if length > linkObfsMaxFrame || padding > linkObfsMaxPadding {
+		return fmt.Errorf("obfuscated frame too large")
+	}
+	if cap(o.rframe) < length+padding+overhead {
+		o.rframe = make([]byte, length+padding+overhead)
+	}
+	body := o.rframe[:length+padding+overhead]
+	if _, err = io.ReadFull(o.Conn, body); err != nil {
+		return err
+	}
+	body, err = o.reader.Open(body[:0], o.rnonce[:], body, nil)
+	if err != nil {
+		return errObfsDecrypt
+	}
+	linkObfsIncrement(&o.rnonce)
+	o.rbuf = body[:length]
+	return nil
+}
+
+func (o *linkObfs) Write(p []byte) (int, error) {
+	o.wmutex.Lock()
+	defer o.wmutex.Unlock()
+	o.wbuf = o.wbuf[:0]
+	if o.writer == nil {
+		var salt [linkObfsSaltLength]byte
+		if _, err := rand.Read(salt[:]); err != nil {
+			return 0, err
+		}
+		writer, err := linkObfsCipher(o.psk, salt[:])
+		if err != nil {
+			return 0, err
+		}
+		o.writer = writer
+		o.wbuf = append(o.wbuf, salt[:]...)
+	}
+	written := 0
+	for len(p) > 0 {
+		chunk := p
+		if len(chunk) > linkObfsMaxFrame {
+			chunk = chunk[:linkObfsMaxFrame]
+		}
+		if err := o.appendFrame(chunk); err != nil {
+			return written, err
+		}
+		if _, err := o.Conn.Write(o.wbuf); err != nil {
+			return written, err
+		}
+		o.wbuf = o.wbuf[:0]
+		written += len(chunk)
+		p = p[len(chunk):]
+	}
+	// If this was the first write and there was no data, then the salt is
+	// still waiting to be sent, and the remote side can't decrypt anything
+	// that follows without it.
+	if len(o.wbuf) > 0 {
+		if _, err := o.Conn.Write(o.wbuf); err != nil {
+			return written, err
+		}
+		o.wbuf = o.wbuf[:0]
+	}
+	return written, nil
+}
+
+// appendFrame encrypts a frame containing the given data and a random amount
+// of padding, and appends it to wbuf.
+func (o *linkObfs) appendFrame(data []byte) error {
+	pad, err := rand.Int(rand.Reader, big.NewInt(linkObfsMaxPadding+1))
+	if err != nil {
+		return err
+	}
+	padding := int(pad.Int64())
+	var header [linkObfsHeaderLen]byte
+	binary.BigEndian.PutUint16(header[0:2], uint16(len(data)))
+	binary.BigEndian.PutUint16(header[2:4], uint16(padding))
+	o.wbuf = o.writer.Seal(o.wbuf, o.wnonce[:], header[:], nil)
+	linkObfsIncrement(&o.wnonce)
+	start := len(o.wbuf)
+	o.wbuf = append(o.wbuf, data...)
+	o.wbuf = append(o.wbuf, make([]byte, padding)...)
+	o.wbuf = o.writer.Seal(o.wbuf[:start], o.wnonce[:], o.wbuf[start:], nil)
+	linkObfsIncrement(&o.wnonce)
+	return nil
+}
@@ -0,0 +1,65 @@
+package core
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"net"
+	"testing"
+)
+
+func TestLinkObfs_RoundTrip(t *testing.T) {
+	connA, connB := net.Pipe()
+	a, b := newLinkObfs(connA, []byte("key")), newLinkObfs(connB, []byte("key"))
+	defer a.Close()
+	defer b.Close()
+
+	large := make([]byte, linkObfsMaxFrame*2+100)
+	if _, err := rand.Read(large); err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		// The first write has no data, but still has to send the salt.
+		for _, msg := range [][]byte{{}, []byte("hello"), large} {
+			if _, err := a.Write(msg); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	for _, msg := range [][]byte{[]byte("hello"), large} {
+		received := make([]byte, len(msg))
+		if _, err := io.ReadFull(b, received); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(received, msg) {
+			t.Fatalf("received %d bytes that don't match what was sent", len(received))
+		}
+	}
+}
+
+func TestLinkObfs_WrongKey(t *testing.T) {
+	connA, connB := net.Pipe()
+	a, b := newLinkObfs(connA, []byte("key")), newLinkObfs(connB, []byte("wrong"))
+	defer a.Close()
+	defer b.Close()
+
+	go func() {
+		_, _ = a.Write([]byte("hello"))
+	}()
+	if _, err := b.Read(make([]byte, 16)); !errors.Is(err, errObfsDecrypt) {
+		t.Fatalf("expected a decryption error, got %v", err)
+	}
+}
+
+// TestLink_Obfs peers two nodes with obfuscation, which covers the handshake
+// going through it too.
+func TestLink_Obfs(t *testing.T) {
+	nodeA, nodeB := newTestNode(t), newTestNode(t)
+	addr := listenTestNode(t, nodeA, "tcp://127.0.0.1:0?obfs=key")
+	callTestNode(t, nodeB, "tcp://"+addr+"?obfs=key")
+	if !waitForPeers(nodeA, 1) || !waitForPeers(nodeB, 1) {
+		t.Fatal("nodes did not peer with obfuscation")
+	}
+}
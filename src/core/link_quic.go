@@ -0,0 +1,217 @@
+package core
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Arceliar/phony"
+	"github.com/quic-go/quic-go"
+)
+
+type linkQUIC struct {
+	phony.Inbox
+	*links
+	tlsconfig  *tls.Config
+	quicconfig *quic.Config
+	_listeners map[*Listener]context.CancelFunc
+}
+
+// linkQUICStream combines a QUIC connection with the single bidirectional
+// stream that carries the link, so that it can be used as a net.Conn.
+type linkQUICStream struct {
+	quic.Connection
+	quic.Stream
+}
+
+// Close closes both the stream and the underlying QUIC connection, as closing
+// the stream by itself only closes the sending direction.
+func (s *linkQUICStream) Close() error {
+	_ = s.Stream.Close()
+	return s.Connection.CloseWithError(0, "")
+}
+
+// linkQUICListener adapts a QUIC listener to a net.Listener. Each accepted
+// QUIC connection is expected to open exactly one stream, which is returned
+// from Accept once it arrives.
+type linkQUICListener struct {
+	*quic.EarlyListener
+	ctx    context.Context
+	cancel context.CancelFunc
+	ch     chan *linkQUICStream
+}
+
+func (l *linkQUICListener) Accept() (net.Conn, error) {
+	select {
+	case qs := <-l.ch:
+		return qs, nil
+	case <-l.ctx.Done():
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *linkQUICListener) Close() error {
+	l.cancel()
+	return l.EarlyListener.Close()
+}
+
+func (l *links) newLinkQUIC() *linkQUIC {
+	lt := &linkQUIC{
+		links:     l,
+		tlsconfig: l.tls.config.Clone(),
+		quicconfig: &quic.Config{
+			HandshakeIdleTimeout: time.Second * 6,
+			MaxIdleTimeout:       time.Minute,
+			KeepAlivePeriod:      time.Second * 20,
+			MaxIncomingStreams:   1,
+			Allow0RTT:            true,
+		},
+		_listeners: map[*Listener]context.CancelFunc{},
+	}
+	lt.tlsconfig.NextProtos = []string{"yggdrasil"}
+	lt.tlsconfig.ClientSessionCache = tls.NewLRUClientSessionCache(0)
+	return lt
+}
+
+func (l *linkQUIC) dial(url *url.URL, options linkOptions, sintf, sni string) error {
+	if sintf != "" {
+		return fmt.Errorf("QUIC peerings do not support a source interface")
+	}
+	info := linkInfoFor("quic", sintf, url.Host)
+	if l.links.isConnectedTo(info) {
+		return nil
+	}
+	tlsconfig := l.tlsconfig.Clone()
+	tlsconfig.ServerName = sni
+	ctx, cancel := context.WithTimeout(l.core.ctx, time.Second*6)
+	defer cancel()
+	qc, err := quic.DialAddrEarly(ctx, url.Host, tlsconfig, l.quicconfig)
+	if err != nil {
+		return err
+	}
+	qs, err := qc.OpenStreamSync(ctx)
+	if err != nil {
+		_ = qc.CloseWithError(0, "")
+		return err
+	}
+	name := strings.TrimRight(strings.SplitN(url.String(), "?", 2)[0], "/")
+	dial := &linkDial{
+		url:   url,
+		sintf: sintf,
+	}
+	return l.handler(dial, name, info, &linkQUICStream{qc, qs}, options, false, false)
+}
+
+func (l *linkQUIC) listen(url *url.URL, sintf string) (*Listener, error) {
+	hostport := url.Host
+	if sintf != "" {
+		if host, port, err := net.SplitHostPort(hostport); err == nil {
+			hostport = fmt.Sprintf("[%s%%%s]:%s", host, sintf, port)
+		}
+	}
+	// The listener runs on a transport of our own, rather than one created by
+	// quic.ListenAddrEarly, as that would close the UDP socket, and with it
+	// every connection that was accepted, as soon as the listener is closed.
+	udpaddr, err := net.ResolveUDPAddr("udp", hostport)
+	if err != nil {
+		return nil, err
+	}
+	udpconn, err := net.ListenUDP("udp", udpaddr)
+	if err != nil {
+		return nil, err
+	}
+	transport := &quic.Transport{Conn: udpconn}
+	ql, err := transport.ListenEarly(l.tlsconfig, l.quicconfig)
+	if err != nil {
+		_ = udpconn.Close()
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(l.core.ctx)
+	listener := &linkQUICListener{
+		EarlyListener: ql,
+		ctx:           ctx,
+		cancel:        cancel,
+		ch:            make(chan *linkQUICStream),
+	}
+	entry := &Listener{
+		Listener: listener,
+		closed:   make(chan struct{}),
+	}
+	phony.Block(l, func() {
+		l._listeners[entry] = cancel
+	})
+	l.core.log.Printf("QUIC listener started on %s", listener.Addr())
+	go func() {
+		// Once the listener has stopped, the transport is kept open until the
+		// connections that it accepted have all closed.
+		var accepted sync.WaitGroup
+		defer func() {
+			accepted.Wait()
+			_ = transport.Close()
+			_ = udpconn.Close()
+		}()
+		for {
+			qc, err := ql.Accept(ctx)
+			if err != nil {
+				cancel()
+				return
+			}
+			accepted.Add(1)
+			go func() {
+				<-qc.Context().Done()
+				accepted.Done()
+			}()
+			go func() {
+				qs, err := qc.AcceptStream(ctx)
+				if err != nil {
+					_ = qc.CloseWithError(0, "")
+					return
+				}
+				select {
+				case listener.ch <- &linkQUICStream{qc, qs}:
+				case <-ctx.Done():
+					_ = qc.CloseWithError(0, "")
+				}
+			}()
+		}
+	}()
+	go func() {
+		defer phony.Block(l, func() {
+			delete(l._listeners, entry)
+		})
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				cancel()
+				break
+			}
+			raddr := conn.RemoteAddr().(*net.UDPAddr)
+			name := fmt.Sprintf("quic://%s", raddr)
+			info := linkInfoFor("quic", sintf, raddr.String())
+			if err = l.handler(nil, name, info, conn, linkOptionsForListener(url), true, raddr.IP.IsLinkLocalUnicast()); err != nil {
+				l.core.log.Errorln("Failed to create inbound link:", err)
+			}
+		}
+		_ = listener.Close()
+		close(entry.closed)
+		l.core.log.Printf("QUIC listener stopped on %s", listener.Addr())
+	}()
+	return entry, nil
+}
+
+func (l *linkQUIC) handler(dial *linkDial, name string, info linkInfo, conn net.Conn, options linkOptions, incoming, force bool) error {
+	return l.links.create(
+		conn,     // connection
+		dial,     // connection URL
+		name,     // connection name
+		info,     // connection info
+		incoming, // not incoming
+		force,    // not forced
+		options,  // connection options
+	)
+}
@@ -1,10 +1,13 @@
 package core
 
 import (
+	"context"
+	"crypto/tls"
 	"fmt"
 	"net"
 	"net/url"
 	"strings"
+	"time"
 
 	"golang.org/x/net/proxy"
 )
@@ -21,7 +24,12 @@ func (l *links) newLinkSOCKS() *linkSOCKS {
 }
 
 func (l *linkSOCKS) dial(url *url.URL, options linkOptions) error {
-	info := linkInfoFor("socks", "", url.Path)
+	pathtokens := strings.Split(strings.Trim(url.Path, "/"), "/")
+	target := pathtokens[0]
+	if _, _, err := net.SplitHostPort(target); err != nil {
+		return fmt.Errorf("invalid target %q: %w", target, err)
+	}
+	info := linkInfoForURL(url, "")
 	if l.links.isConnectedTo(info) {
 		return nil
 	}
@@ -32,11 +40,24 @@ func (l *linkSOCKS) dial(url *url.URL, options linkOptions) error {
 	if err != nil {
 		return fmt.Errorf("failed to configure proxy")
 	}
-	pathtokens := strings.Split(strings.Trim(url.Path, "/"), "/")
-	conn, err := dialer.Dial("tcp", pathtokens[0])
+	// Hostname targets are passed to the proxy to resolve rather than being
+	// resolved locally, which is what allows reaching .onion addresses.
+	conn, err := dialer.Dial("tcp", target)
 	if err != nil {
 		return err
 	}
+	if url.Scheme == "sockstls" {
+		tlsconfig := l.tls.config.Clone()
+		tlsconfig.ServerName = tlsSNIFor(url, target)
+		tlsconn := tls.Client(conn, tlsconfig)
+		ctx, cancel := context.WithTimeout(l.core.ctx, time.Second*6)
+		defer cancel()
+		if err = tlsconn.HandshakeContext(ctx); err != nil {
+			_ = conn.Close()
+			return err
+		}
+		conn = tlsconn
+	}
 	dial := &linkDial{
 		url: url,
 	}
@@ -44,13 +65,14 @@ func (l *linkSOCKS) dial(url *url.URL, options linkOptions) error {
 }
 
 func (l *linkSOCKS) handler(dial *linkDial, info linkInfo, conn net.Conn, options linkOptions, incoming bool) error {
+	name := linkNameForURL(dial.url)
 	return l.links.create(
-		conn,              // connection
-		dial,              // connection URL
-		dial.url.String(), // connection name
-		info,              // connection info
-		incoming,          // not incoming
-		false,             // not forced
-		options,           // connection options
+		conn,     // connection
+		dial,     // connection URL
+		name,     // connection name
+		info,     // connection info
+		incoming, // not incoming
+		false,    // not forced
+		options,  // connection options
 	)
 }
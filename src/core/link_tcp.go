@@ -81,21 +81,114 @@ func (l *linkTCP) dial(url *url.URL, options linkOptions, sintf string) error {
 	if len(dialers) == 0 {
 		return nil
 	}
+	d, conn, err := l.dialParallel(dialers, func(ctx context.Context, d *tcpDialer) (net.Conn, error) {
+		return d.dialer.DialContext(ctx, "tcp", d.addr.String())
+	})
+	if err != nil {
+		return err
+	}
+	name := strings.TrimRight(strings.SplitN(url.String(), "?", 2)[0], "/")
+	dial := &linkDial{
+		url:   url,
+		sintf: sintf,
+	}
+	return l.handler(dial, name, d.info, conn, options, false, false)
+}
+
+// The delay between starting connection attempts to successive addresses,
+// as recommended by RFC 8305.
+const tcpConnectionAttemptDelay = time.Millisecond * 250
+
+// dialParallel connects to the given addresses using the "Happy Eyeballs"
+// algorithm from RFC 8305. Addresses are tried in order, alternating between
+// IPv6 and IPv4 starting with IPv6, with the next attempt starting either when
+// the previous one fails or after a short delay, whichever is sooner. The first
+// connection to complete wins and all other attempts are cancelled.
+//
+// Note that the race is won by the first TCP (or TLS) connection to be
+// established, not by the first to complete the Yggdrasil handshake. If the
+// winning address accepts connections but is not a working Yggdrasil node,
+// the handshake fails and the peering is retried on the usual backoff, which
+// runs the race again, rather than falling back to the remaining addresses.
+func (l *linkTCP) dialParallel(dialers []*tcpDialer, connect func(context.Context, *tcpDialer) (net.Conn, error)) (*tcpDialer, net.Conn, error) {
+	var v6, v4 []*tcpDialer
 	for _, d := range dialers {
-		var conn net.Conn
-		conn, err = d.dialer.DialContext(l.core.ctx, "tcp", d.addr.String())
-		if err != nil {
-			l.core.log.Warnf("Failed to connect to %s: %s", d.addr, err)
-			continue
+		if d.addr.IP.To4() != nil {
+			v4 = append(v4, d)
+		} else {
+			v6 = append(v6, d)
+		}
+	}
+	ordered := make([]*tcpDialer, 0, len(dialers))
+	for i := 0; i < len(v6) || i < len(v4); i++ {
+		if i < len(v6) {
+			ordered = append(ordered, v6[i])
 		}
-		name := strings.TrimRight(strings.SplitN(url.String(), "?", 2)[0], "/")
-		dial := &linkDial{
-			url:   url,
-			sintf: sintf,
+		if i < len(v4) {
+			ordered = append(ordered, v4[i])
+		}
+	}
+	type result struct {
+		dialer *tcpDialer
+		conn   net.Conn
+		err    error
+	}
+	ctx, cancel := context.WithCancel(l.core.ctx)
+	defer cancel()
+	results := make(chan result, len(ordered))
+	var next, pending int
+	var delay <-chan time.Time
+	start := func() {
+		d := ordered[next]
+		next++
+		pending++
+		go func() {
+			conn, err := connect(ctx, d)
+			results <- result{d, conn, err}
+		}()
+		if next < len(ordered) {
+			delay = time.After(tcpConnectionAttemptDelay)
+		} else {
+			delay = nil
+		}
+	}
+	start()
+	var err error
+	for pending > 0 {
+		select {
+		case <-delay:
+			start()
+		case r := <-results:
+			pending--
+			if r.err != nil {
+				l.core.log.Warnf("Failed to connect to %s: %s", r.dialer.addr, r.err)
+				err = r.err
+				if next < len(ordered) {
+					start()
+				}
+				continue
+			}
+			// Any attempts that are still in flight have been cancelled, but
+			// they may still have completed in the meantime, in which case
+			// they need to be closed.
+			go func(pending int) {
+				for ; pending > 0; pending-- {
+					if r := <-results; r.conn != nil {
+						_ = r.conn.Close()
+					}
+				}
+			}(pending)
+			if len(ordered) > 1 {
+				family := "IPv6"
+				if r.dialer.addr.IP.To4() != nil {
+					family = "IPv4"
+				}
+				l.core.log.Infof("Connected to %s using %s, out of %d address(es)", r.dialer.addr, family, len(ordered))
+			}
+			return r.dialer, r.conn, nil
 		}
-		return l.handler(dial, name, d.info, conn, options, false, false)
 	}
-	return fmt.Errorf("failed to connect via %d address(es), last error: %w", len(dialers), err)
+	return nil, nil, fmt.Errorf("failed to connect via %d address(es), last error: %w", len(ordered), err)
 }
 
 func (l *linkTCP) listen(url *url.URL, sintf string) (*Listener, error) {
@@ -0,0 +1,86 @@
+package core
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// TestLink_TCPDialParallel races a dead address, which is tried first as it
+// is IPv6, against a live one, and checks that the live one wins and that the
+// handshake completes over it.
+func TestLink_TCPDialParallel(t *testing.T) {
+	nodeA, nodeB := newTestNode(t), newTestNode(t)
+	addr := listenTestNode(t, nodeA, "tcp://127.0.0.1:0")
+	live, err := net.ResolveTCPAddr("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dead := &net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: live.Port}
+	dialers := []*tcpDialer{
+		{info: linkInfoFor("tcp", "", dead.String()), dialer: &net.Dialer{}, addr: dead},
+		{info: linkInfoFor("tcp", "", live.String()), dialer: &net.Dialer{}, addr: live},
+	}
+
+	for name, deadConnect := range map[string]func(context.Context) (net.Conn, error){
+		// The dead address refuses the connection straight away.
+		"refused": func(_ context.Context) (net.Conn, error) {
+			return nil, &net.OpError{Op: "dial", Net: "tcp", Addr: dead, Err: net.UnknownNetworkError("refused")}
+		},
+		// The dead address never answers, so the live one is only tried
+		// after the attempt delay, and the dead attempt must be cancelled.
+		"blackhole": func(ctx context.Context) (net.Conn, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			start := time.Now()
+			d, conn, err := nodeB.links.tcp.dialParallel(dialers, func(ctx context.Context, d *tcpDialer) (net.Conn, error) {
+				if d.addr == dead {
+					return deadConnect(ctx)
+				}
+				return d.dialer.DialContext(ctx, "tcp", d.addr.String())
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d.addr != live {
+				_ = conn.Close()
+				t.Fatalf("expected %s to win, got %s", live, d.addr)
+			}
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Fatalf("connecting took %s", elapsed)
+			}
+			_ = conn.Close()
+		})
+	}
+
+	d, conn, err := nodeB.links.tcp.dialParallel(dialers[:1], func(ctx context.Context, d *tcpDialer) (net.Conn, error) {
+		return nil, &net.OpError{Op: "dial", Net: "tcp", Addr: dead, Err: net.UnknownNetworkError("refused")}
+	})
+	if err == nil || d != nil || conn != nil {
+		t.Fatal("expected dialling only the dead address to fail")
+	}
+
+	// The winning connection carries the handshake.
+	d, conn, err = nodeB.links.tcp.dialParallel(dialers, func(ctx context.Context, d *tcpDialer) (net.Conn, error) {
+		if d.addr == dead {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return d.dialer.DialContext(ctx, "tcp", d.addr.String())
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse("tcp://" + addr)
+	go func() {
+		_ = nodeB.links.tcp.handler(&linkDial{url: u}, "tcp://"+addr, d.info, conn, linkOptions{}, false, false)
+	}()
+	if !waitForPeers(nodeA, 1) || !waitForPeers(nodeB, 1) {
+		t.Fatal("nodes did not peer over the winning connection")
+	}
+}
@@ -1,19 +1,18 @@
 package core
 
 import (
-	"bytes"
 	"context"
 	"crypto/rand"
 	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/hex"
-	"encoding/pem"
 	"fmt"
 	"math/big"
 	"net"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Arceliar/phony"
@@ -55,26 +54,24 @@ func (l *linkTLS) dial(url *url.URL, options linkOptions, sintf, sni string) err
 	if len(dialers) == 0 {
 		return nil
 	}
-	for _, d := range dialers {
-		tlsconfig := l.config.Clone()
-		tlsconfig.ServerName = sni
+	tlsconfig := l.config.Clone()
+	tlsconfig.ServerName = sni
+	d, conn, err := l.tcp.dialParallel(dialers, func(ctx context.Context, d *tcpDialer) (net.Conn, error) {
 		tlsdialer := &tls.Dialer{
 			NetDialer: d.dialer,
 			Config:    tlsconfig,
 		}
-		var conn net.Conn
-		conn, err = tlsdialer.DialContext(l.core.ctx, "tcp", d.addr.String())
-		if err != nil {
-			continue
-		}
-		name := strings.TrimRight(strings.SplitN(url.String(), "?", 2)[0], "/")
-		dial := &linkDial{
-			url:   url,
-			sintf: sintf,
-		}
-		return l.handler(dial, name, d.info, conn, options, false, false)
+		return tlsdialer.DialContext(ctx, "tcp", d.addr.String())
+	})
+	if err != nil {
+		return err
 	}
-	return fmt.Errorf("failed to connect via %d address(es), last error: %w", len(dialers), err)
+	name := strings.TrimRight(strings.SplitN(url.String(), "?", 2)[0], "/")
+	dial := &linkDial{
+		url:   url,
+		sintf: sintf,
+	}
+	return l.handler(dial, name, d.info, conn, options, false, false)
 }
 
 func (l *linkTLS) listen(url *url.URL, sintf string) (*Listener, error) {
@@ -136,8 +133,56 @@ func (l *linkTLS) listen(url *url.URL, sintf string) (*Listener, error) {
 // RFC5280 section 4.1.2.5
 var notAfterNeverExpires = time.Date(9999, time.December, 31, 23, 59, 59, 0, time.UTC)
 
+// The TLSCertificateSubject value that asks for a random certificate subject.
+const tlsRandomSubject = "random"
+
+// Certificates with a configured subject are valid for the same length of time
+// as those from public CAs, and are replaced once they are this close to expiry.
+const (
+	tlsCertificateLifetime    = time.Hour * 24 * 90
+	tlsCertificateRenewBefore = time.Hour * 24 * 30
+)
+
 func (l *linkTLS) generateConfig() (*tls.Config, error) {
-	certBuf := &bytes.Buffer{}
+	subject := l.links.core.config.tlsSubject
+	if subject == tlsRandomSubject {
+		var err error
+		if subject, err = tlsRandomHostname(); err != nil {
+			return nil, err
+		}
+	}
+	cert, err := l.generateCertificate(subject)
+	if err != nil {
+		return nil, err
+	}
+
+	rootCAs := x509.NewCertPool()
+	rootCAs.AppendCertsFromPEM(cert.Certificate[0])
+
+	config := &tls.Config{
+		RootCAs:            rootCAs,
+		InsecureSkipVerify: true,
+		MinVersion:         tls.VersionTLS13,
+	}
+	if subject == "" {
+		config.Certificates = []tls.Certificate{*cert}
+	} else {
+		renewing := &tlsRenewingCertificate{
+			cert: cert,
+			generate: func() (*tls.Certificate, error) {
+				return l.generateCertificate(subject)
+			},
+		}
+		config.GetCertificate = renewing.GetCertificate
+	}
+	return config, nil
+}
+
+// generateCertificate generates a self-signed certificate for our key. The
+// default certificate is easy to recognise, since the subject is our public
+// key and it never expires. If a subject is given then the certificate is made
+// to look more like any other instead.
+func (l *linkTLS) generateCertificate(subject string) (*tls.Certificate, error) {
 	cert := x509.Certificate{
 		SerialNumber: big.NewInt(1),
 		Subject: pkix.Name{
@@ -149,35 +194,109 @@ func (l *linkTLS) generateConfig() (*tls.Config, error) {
 		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
 		BasicConstraintsValid: true,
 	}
+	if subject != "" {
+		serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 127))
+		if err != nil {
+			return nil, err
+		}
+		cert.SerialNumber = serial
+		cert.Subject.CommonName = subject
+		cert.DNSNames = []string{subject}
+		cert.NotBefore = time.Now().Add(-time.Hour * 24).Truncate(time.Hour)
+		cert.NotAfter = cert.NotBefore.Add(tlsCertificateLifetime)
+	}
 
 	certbytes, err := x509.CreateCertificate(rand.Reader, &cert, &cert, l.links.core.public, l.links.core.secret)
 	if err != nil {
 		return nil, err
 	}
-
-	if err := pem.Encode(certBuf, &pem.Block{
-		Type:  "CERTIFICATE",
-		Bytes: certbytes,
-	}); err != nil {
+	leaf, err := x509.ParseCertificate(certbytes)
+	if err != nil {
 		return nil, err
 	}
-
-	rootCAs := x509.NewCertPool()
-	rootCAs.AppendCertsFromPEM(certbytes)
-
-	return &tls.Config{
-		RootCAs: rootCAs,
-		Certificates: []tls.Certificate{
-			{
-				Certificate: [][]byte{certbytes},
-				PrivateKey:  l.links.core.secret,
-			},
-		},
-		InsecureSkipVerify: true,
-		MinVersion:         tls.VersionTLS13,
+	return &tls.Certificate{
+		Certificate: [][]byte{certbytes},
+		PrivateKey:  l.links.core.secret,
+		Leaf:        leaf,
 	}, nil
 }
 
+// tlsRenewingCertificate hands out a certificate with a limited lifetime to
+// TLS servers, replacing it with a new one before it expires.
+type tlsRenewingCertificate struct {
+	mutex    sync.Mutex
+	cert     *tls.Certificate
+	generate func() (*tls.Certificate, error)
+}
+
+func (r *tlsRenewingCertificate) GetCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if time.Now().After(r.cert.Leaf.NotAfter.Add(-tlsCertificateRenewBefore)) {
+		cert, err := r.generate()
+		if err != nil {
+			return nil, err
+		}
+		r.cert = cert
+	}
+	return r.cert, nil
+}
+
+// tlsSNIFor returns the SNI hostname to send when dialling the given peering
+// URI, or an empty string if there is no suitable hostname. The hostport is the
+// address of the remote node, which is usually the host part of the URI but may
+// be somewhere else in the URI when connecting through a proxy. SNI headers must
+// contain hostnames and not IP addresses, so we must make sure that we do not
+// populate the SNI with an IP literal. We do this by splitting the host-port
+// combo from the query option and then seeing if it parses to an IP address
+// successfully or not.
+func tlsSNIFor(u *url.URL, hostport string) string {
+	if sni := u.Query().Get("sni"); sni != "" {
+		if net.ParseIP(sni) == nil {
+			return sni
+		}
+	}
+	// If the SNI is not configured still because the above failed then we'll try
+	// again but this time we'll use the host part of the remote address instead.
+	if host, _, err := net.SplitHostPort(hostport); err == nil && net.ParseIP(host) == nil {
+		return host
+	}
+	return ""
+}
+
 func (l *linkTLS) handler(dial *linkDial, name string, info linkInfo, conn net.Conn, options linkOptions, incoming, force bool) error {
 	return l.tcp.handler(dial, name, info, conn, options, incoming, force)
 }
+
+// tlsRandomHostname returns a random hostname to use as the certificate subject
+// when TLSCertificateSubject is set to "random".
+func tlsRandomHostname() (string, error) {
+	const letters = "abcdefghijklmnopqrstuvwxyz"
+	tlds := []string{"com", "net", "org", "io"}
+	length, err := tlsRandomInt(6)
+	if err != nil {
+		return "", err
+	}
+	name := make([]byte, 6+length)
+	for i := range name {
+		letter, err := tlsRandomInt(len(letters))
+		if err != nil {
+			return "", err
+		}
+		name[i] = letters[letter]
+	}
+	tld, err := tlsRandomInt(len(tlds))
+	if err != nil {
+		return "", err
+	}
+	return string(name) + "." + tlds[tld], nil
+}
+
+// tlsRandomInt returns a uniformly random number between 0 and n-1.
+func tlsRandomInt(n int) (int, error) {
+	v, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+	return int(v.Int64()), nil
+}
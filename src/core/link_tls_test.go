@@ -0,0 +1,64 @@
+package core
+
+import (
+	"crypto/tls"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestLinkTLS_RandomHostname(t *testing.T) {
+	valid := regexp.MustCompile(`^[a-z]{6,11}\.(com|net|org|io)$`)
+	for i := 0; i < 100; i++ {
+		name, err := tlsRandomHostname()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !valid.MatchString(name) {
+			t.Fatalf("unexpected random hostname %q", name)
+		}
+	}
+}
+
+func TestLinkTLS_CertificateSubject(t *testing.T) {
+	c := newTestNode(t, TLSCertificateSubject("example.com"))
+	config := c.links.tls.config
+	if config.GetCertificate == nil {
+		t.Fatal("certificate with a subject is not renewed")
+	}
+	cert, err := config.GetCertificate(&tls.ClientHelloInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cert.Leaf.Subject.CommonName != "example.com" {
+		t.Fatalf("unexpected certificate subject %q", cert.Leaf.Subject.CommonName)
+	}
+	if lifetime := cert.Leaf.NotAfter.Sub(cert.Leaf.NotBefore); lifetime != tlsCertificateLifetime {
+		t.Fatalf("unexpected certificate lifetime %s", lifetime)
+	}
+	if again, _ := config.GetCertificate(&tls.ClientHelloInfo{}); again != cert {
+		t.Fatal("certificate was replaced before it was due")
+	}
+
+	// Pretend that the certificate is close to expiry.
+	cert.Leaf.NotAfter = time.Now().Add(tlsCertificateRenewBefore - time.Hour)
+	renewed, err := config.GetCertificate(&tls.ClientHelloInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renewed == cert || renewed.Leaf.Subject.CommonName != "example.com" {
+		t.Fatal("certificate was not renewed before expiry")
+	}
+	if renewed.Leaf.NotAfter.Before(time.Now().Add(tlsCertificateRenewBefore)) {
+		t.Fatalf("renewed certificate expires at %s", renewed.Leaf.NotAfter)
+	}
+}
+
+func TestLink_TLSCertificateSubject(t *testing.T) {
+	nodeA, nodeB := newTestNode(t, TLSCertificateSubject(tlsRandomSubject)), newTestNode(t)
+	addr := listenTestNode(t, nodeA, "tls://127.0.0.1:0")
+	callTestNode(t, nodeB, "tls://"+addr)
+	if !waitForPeers(nodeA, 1) || !waitForPeers(nodeB, 1) {
+		t.Fatal("nodes did not peer over TLS with a random certificate subject")
+	}
+}
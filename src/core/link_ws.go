@@ -0,0 +1,222 @@
+package core
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Arceliar/phony"
+	"github.com/coder/websocket"
+	"github.com/pires/go-proxyproto"
+)
+
+// The largest single WebSocket message that we will accept from the remote
+// side. This is much larger than anything we should ever send ourselves, but
+// stops a misbehaving client from making us buffer arbitrarily large messages.
+const linkWSReadLimit = 1024 * 1024
+
+// The WebSocket subprotocol that both sides must agree on.
+const linkWSSubprotocol = "ygg-ws"
+
+type linkWS struct {
+	phony.Inbox
+	*links
+	listener   *net.ListenConfig
+	_listeners map[*Listener]context.CancelFunc
+}
+
+// linkWSListener adapts an HTTP server that upgrades requests to WebSockets
+// into a net.Listener, so that it can be handled in the same way as the other
+// listener types.
+type linkWSListener struct {
+	net.Listener
+	server  *http.Server
+	path    string
+	ctx     context.Context // cancelled when the listener stops
+	connctx context.Context // bounds the lifetime of accepted connections
+	cancel  context.CancelFunc
+	ch      chan net.Conn
+}
+
+func (l *linkWSListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.ch:
+		return conn, nil
+	case <-l.ctx.Done():
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *linkWSListener) Close() error {
+	l.cancel()
+	return l.server.Close()
+}
+
+func (l *linkWSListener) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != l.path {
+		http.NotFound(w, r)
+		return
+	}
+	c, err := websocket.Accept(w, r, &websocket.AcceptOptions{
+		Subprotocols: []string{linkWSSubprotocol},
+	})
+	if err != nil {
+		return
+	}
+	if c.Subprotocol() != linkWSSubprotocol {
+		_ = c.Close(websocket.StatusPolicyViolation, "client must speak the "+linkWSSubprotocol+" subprotocol")
+		return
+	}
+	c.SetReadLimit(linkWSReadLimit)
+	conn := websocket.NetConn(l.connctx, c, websocket.MessageBinary)
+	select {
+	case l.ch <- conn:
+	case <-l.ctx.Done():
+		_ = conn.Close()
+	}
+}
+
+func (l *links) newLinkWS() *linkWS {
+	lt := &linkWS{
+		links: l,
+		listener: &net.ListenConfig{
+			Control:   l.tcp.tcpContext,
+			KeepAlive: -1,
+		},
+		_listeners: map[*Listener]context.CancelFunc{},
+	}
+	return lt
+}
+
+func (l *linkWS) dial(url *url.URL, options linkOptions, sintf, sni string) error {
+	if sintf != "" {
+		return fmt.Errorf("WebSocket peerings do not support a source interface")
+	}
+	info := linkInfoForURL(url, sintf)
+	if l.links.isConnectedTo(info) {
+		return nil
+	}
+	// The query string contains our own peering options, which are of no
+	// interest to the remote side or to any proxies along the way.
+	wsurl := *url
+	wsurl.RawQuery = ""
+	wsurl.Fragment = ""
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = l.tls.config.Clone()
+	transport.TLSClientConfig.ServerName = sni
+	ctx, cancel := context.WithTimeout(l.core.ctx, time.Second*6)
+	defer cancel()
+	c, _, err := websocket.Dial(ctx, wsurl.String(), &websocket.DialOptions{
+		HTTPClient:   &http.Client{Transport: transport},
+		Subprotocols: []string{linkWSSubprotocol},
+	})
+	if err != nil {
+		return err
+	}
+	c.SetReadLimit(linkWSReadLimit)
+	conn := websocket.NetConn(l.core.ctx, c, websocket.MessageBinary)
+	name := linkNameForURL(url)
+	dial := &linkDial{
+		url:   url,
+		sintf: sintf,
+	}
+	return l.handler(dial, name, info, conn, options, false, false)
+}
+
+func (l *linkWS) listen(url *url.URL, sintf string) (*Listener, error) {
+	ctx, cancel := context.WithCancel(l.core.ctx)
+	hostport := url.Host
+	if sintf != "" {
+		if host, port, err := net.SplitHostPort(hostport); err == nil {
+			hostport = fmt.Sprintf("[%s%%%s]:%s", host, sintf, port)
+		}
+	}
+	tcplistener, err := l.listener.Listen(ctx, "tcp", hostport)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	linkoptions := linkOptionsForListener(url)
+	var httplistener net.Listener = tcplistener
+	if linkoptions.proxyprotocol {
+		httplistener = &proxyproto.Listener{Listener: httplistener}
+		l.core.log.Printf("ProxyProtocol enabled for %s listener %s", strings.ToUpper(url.Scheme), tcplistener.Addr())
+	}
+	if url.Scheme == "wss" {
+		httplistener = tls.NewListener(httplistener, l.tls.config)
+	}
+	path := url.Path
+	if path == "" {
+		path = "/"
+	}
+	listener := &linkWSListener{
+		Listener: httplistener,
+		path:     path,
+		ctx:      ctx,
+		connctx:  l.core.ctx,
+		cancel:   cancel,
+		ch:       make(chan net.Conn),
+	}
+	listener.server = &http.Server{
+		Handler:           listener,
+		ReadHeaderTimeout: time.Second * 6,
+		BaseContext: func(_ net.Listener) context.Context {
+			return ctx
+		},
+	}
+	entry := &Listener{
+		Listener: listener,
+		closed:   make(chan struct{}),
+	}
+	phony.Block(l, func() {
+		l._listeners[entry] = cancel
+	})
+	l.core.log.Printf("%s listener started on %s%s", strings.ToUpper(url.Scheme), tcplistener.Addr(), path)
+	go func() {
+		_ = listener.server.Serve(httplistener)
+		cancel()
+	}()
+	go func() {
+		defer phony.Block(l, func() {
+			delete(l._listeners, entry)
+		})
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				cancel()
+				break
+			}
+			raddr := conn.RemoteAddr()
+			name := fmt.Sprintf("%s://%s", url.Scheme, raddr)
+			info := linkInfoFor(url.Scheme, sintf, raddr.String())
+			force := false
+			if tcpaddr, ok := raddr.(*net.TCPAddr); ok {
+				force = tcpaddr.IP.IsLinkLocalUnicast()
+			}
+			if err = l.handler(nil, name, info, conn, linkoptions, true, force); err != nil {
+				l.core.log.Errorln("Failed to create inbound link:", err)
+			}
+		}
+		_ = listener.Close()
+		close(entry.closed)
+		l.core.log.Printf("%s listener stopped on %s%s", strings.ToUpper(url.Scheme), tcplistener.Addr(), path)
+	}()
+	return entry, nil
+}
+
+func (l *linkWS) handler(dial *linkDial, name string, info linkInfo, conn net.Conn, options linkOptions, incoming, force bool) error {
+	return l.links.create(
+		conn,     // connection
+		dial,     // connection URL
+		name,     // connection name
+		info,     // connection info
+		incoming, // not incoming
+		force,    // not forced
+		options,  // connection options
+	)
+}
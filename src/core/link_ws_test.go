@@ -0,0 +1,76 @@
+package core
+
+import (
+	"context"
+	"net"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// TestLink_WS runs the WebSocket listener behind an httptest server, which
+// hands the upgraded connections to a node, and peers with it from another
+// node over ws://.
+func TestLink_WS(t *testing.T) {
+	nodeA, nodeB := newTestNode(t), newTestNode(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	listener := &linkWSListener{
+		path:    "/ygg",
+		ctx:     ctx,
+		connctx: ctx,
+		cancel:  cancel,
+		ch:      make(chan net.Conn),
+	}
+	server := httptest.NewServer(listener)
+	defer server.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			info := linkInfoFor("ws", "", conn.RemoteAddr().String())
+			_ = nodeA.links.create(conn, nil, "ws://"+conn.RemoteAddr().String(), info, true, false, linkOptions{})
+		}
+	}()
+
+	base := strings.Replace(server.URL, "http://", "ws://user:secret@", 1)
+	callTestNode(t, nodeB, base+"/wrong")
+	callTestNode(t, nodeB, base+"/ygg?password=")
+	if !waitForPeers(nodeA, 1) || !waitForPeers(nodeB, 1) {
+		t.Fatal("nodes did not peer over WebSocket")
+	}
+	peer := nodeB.GetPeers()[0]
+	if strings.Contains(peer.Remote, "secret") || !strings.HasSuffix(peer.Remote, "/ygg") {
+		t.Fatalf("unexpected name for the WebSocket peering: %s", peer.Remote)
+	}
+
+	// Calling the same URI again must be recognised as already connected.
+	u, err := url.Parse(base + "/ygg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !nodeB.links.isConnectedTo(linkInfoForURL(u, "")) {
+		t.Fatal("the WebSocket peering is not found by its URI")
+	}
+}
+
+func TestLink_InfoForURL(t *testing.T) {
+	for _, test := range []struct {
+		a, b string
+		same bool
+	}{
+		{"ws://a.b:80/one", "ws://a.b:80/one?key=abc", true},
+		{"ws://a.b:80/one", "ws://a.b:80/two", false},
+		{"tcp://a.b:80", "tcp://a.b:80?password=x", true},
+		{"tcp://a.b:80", "tls://a.b:80", false},
+	} {
+		a, _ := url.Parse(test.a)
+		b, _ := url.Parse(test.b)
+		if same := linkInfoForURL(a, "") == linkInfoForURL(b, ""); same != test.same {
+			t.Errorf("%s and %s: expected same link=%t", test.a, test.b, test.same)
+		}
+	}
+}
@@ -2,22 +2,79 @@ package core
 
 import (
 	"crypto/ed25519"
+	"net/url"
+	"time"
 )
 
 func (c *Core) _applyOption(opt SetupOption) {
 	switch v := opt.(type) {
 	case Peer:
-		c.config._peers[v] = nil
+		c.config._peers[v] = &peerState{}
 	case ListenAddress:
+		// Keyed in the same form as the running listeners, so that they can
+		// be matched up by RemoveListener.
+		if u, err := url.Parse(string(v)); err == nil {
+			v = ListenAddress(linkListenerURI(u))
+		}
 		c.config._listeners[v] = struct{}{}
 	case NodeInfo:
-		c.config.nodeinfo = v
+		c.config._nodeinfo = v
 	case NodeInfoPrivacy:
-		c.config.nodeinfoPrivacy = v
+		c.config._nodeinfoPrivacy = v
 	case AllowedPublicKey:
 		pk := [32]byte{}
 		copy(pk[:], v)
 		c.config._allowedPublicKeys[pk] = struct{}{}
+	case PeerRetryMinInterval:
+		if v > 0 {
+			c.config.peerRetryMin = time.Duration(v)
+		}
+	case PeerRetryMaxInterval:
+		if v > 0 {
+			c.config.peerRetryMax = time.Duration(v)
+		}
+	case HeartbeatInterval:
+		if v > 0 {
+			c.config.heartbeatInterval = time.Duration(v)
+		}
+	case HeartbeatTimeout:
+		if v > 0 {
+			c.config.heartbeatTimeout = time.Duration(v)
+		}
+	case MaxUploadRate:
+		c.config.maxUpload = uint64(v)
+	case MaxDownloadRate:
+		c.config.maxDownload = uint64(v)
+	case TLSCertificateSubject:
+		c.config.tlsSubject = string(v)
+	case MaxInboundPeers:
+		c.config.maxInbound = uint64(v)
+	case MaxInboundPeersPerSource:
+		c.config.maxInboundPerSource = uint64(v)
+	case InboundHandshakeRate:
+		c.config.inboundHandshakeRate = uint64(v)
+	case BlockedPublicKey:
+		pk := [32]byte{}
+		copy(pk[:], v)
+		c.config._blockedPublicKeys[pk] = struct{}{}
+	case BlockedAddress:
+		if prefix, err := parseBlockedAddress(string(v)); err == nil {
+			c.config._blockedAddresses[prefix.String()] = prefix
+		} else {
+			c.log.Warnf("Ignoring blocked address: %s", err)
+		}
+	case AutoBanThreshold:
+		c.config.autoBanThreshold = uint64(v)
+	case AutoBanDuration:
+		if v > 0 {
+			c.config.autoBanDuration = time.Duration(v)
+		}
+	case PeerList:
+		if source, err := c.newPeerListSource(v); err == nil {
+			c.config._peerSources[Peer{URI: v.URL}] = source
+		} else {
+			c.log.Warnf("Ignoring peer list: %s", err)
+		}
 	}
 }
 
@@ -33,9 +90,44 @@ type Peer struct {
 type NodeInfo map[string]interface{}
 type NodeInfoPrivacy bool
 type AllowedPublicKey ed25519.PublicKey
+type PeerRetryMinInterval time.Duration
+type PeerRetryMaxInterval time.Duration
+type HeartbeatInterval time.Duration
+type HeartbeatTimeout time.Duration
+type MaxUploadRate uint64   // bytes per second across all links, 0 if unlimited
+type MaxDownloadRate uint64 // bytes per second across all links, 0 if unlimited
+type TLSCertificateSubject string
+type MaxInboundPeers uint64          // 0 if unlimited
+type MaxInboundPeersPerSource uint64 // per IPv4 address or IPv6 /64, 0 if unlimited
+type InboundHandshakeRate uint64     // per source per minute, 0 if unlimited
+type BlockedPublicKey ed25519.PublicKey
+type BlockedAddress string   // IP address or prefix in CIDR notation
+type AutoBanThreshold uint64 // handshake failures before a ban, 0 to disable
+type AutoBanDuration time.Duration
+type PeerList struct {
+	URL       string            // http or https URL of the signed list
+	PublicKey ed25519.PublicKey // key of the publisher that signs the list
+	Refresh   time.Duration     // time between fetches, 0 for the default
+	CacheFile string            // where to keep the last good copy, if anywhere
+}
 
-func (a ListenAddress) isSetupOption()    {}
-func (a Peer) isSetupOption()             {}
-func (a NodeInfo) isSetupOption()         {}
-func (a NodeInfoPrivacy) isSetupOption()  {}
-func (a AllowedPublicKey) isSetupOption() {}
+func (a ListenAddress) isSetupOption()            {}
+func (a Peer) isSetupOption()                     {}
+func (a NodeInfo) isSetupOption()                 {}
+func (a NodeInfoPrivacy) isSetupOption()          {}
+func (a AllowedPublicKey) isSetupOption()         {}
+func (a PeerRetryMinInterval) isSetupOption()     {}
+func (a PeerRetryMaxInterval) isSetupOption()     {}
+func (a HeartbeatInterval) isSetupOption()        {}
+func (a HeartbeatTimeout) isSetupOption()         {}
+func (a MaxUploadRate) isSetupOption()            {}
+func (a MaxDownloadRate) isSetupOption()          {}
+func (a TLSCertificateSubject) isSetupOption()    {}
+func (a MaxInboundPeers) isSetupOption()          {}
+func (a MaxInboundPeersPerSource) isSetupOption() {}
+func (a InboundHandshakeRate) isSetupOption()     {}
+func (a BlockedPublicKey) isSetupOption()         {}
+func (a BlockedAddress) isSetupOption()           {}
+func (a AutoBanThreshold) isSetupOption()         {}
+func (a AutoBanDuration) isSetupOption()          {}
+func (a PeerList) isSetupOption()                 {}
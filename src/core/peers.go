@@ -0,0 +1,292 @@
+package core
+
+import (
+	"context"
+	"math/rand"
+	"net/url"
+	"time"
+
+	"github.com/Arceliar/phony"
+)
+
+// The default bounds on the reconnect interval for peers in the peer list,
+// used if they are not set with the PeerRetryMinInterval and
+// PeerRetryMaxInterval options.
+const (
+	defaultPeerRetryMinInterval = time.Second
+	defaultPeerRetryMaxInterval = time.Minute
+)
+
+// peerState tracks the reconnection state of a peer from the peer list. It is
+// owned by the Core actor.
+type peerState struct {
+	url       *url.URL    // parsed once, so that links can be matched back to it
+	info      *linkInfo   // set once the peer has been called
+	connected bool        // true while there is a link that has completed the handshake
+	failures  uint        // number of consecutive failed attempts
+	next      time.Time   // time of the next attempt, zero if none is scheduled
+	timer     *time.Timer // fires at the next attempt
+}
+
+// dialPeer calls a peer from the peer list. Once the outcome of the call is
+// known, it is fed back into the reconnect scheduler. This must not be called
+// from the Core actor, as calling a peer blocks on the links actor.
+func (c *Core) dialPeer(peer Peer, state *peerState) error {
+	errch := make(chan error, 1)
+	info, err := c.links.call(state.url, peer.SourceInterface, errch)
+	if err != nil {
+		return err
+	}
+	c.Act(nil, func() {
+		state.info = &info
+	})
+	go func() {
+		err := <-errch
+		c.Act(nil, func() {
+			if err != nil {
+				c._peerFailed(peer, state)
+			} else {
+				c._peerPending(peer, state)
+			}
+		})
+	}()
+	return nil
+}
+
+// retryPeer is like dialPeer but treats a failure to start the call in the
+// same way as a failed connection, which is what we want for scheduled calls.
+func (c *Core) retryPeer(peer Peer, state *peerState) {
+	if err := c.dialPeer(peer, state); err != nil {
+		c.log.Errorln("Failed to add peer:", err)
+		c.Act(nil, func() {
+			c._peerFailed(peer, state)
+		})
+	}
+}
+
+// _removePeer removes a peer from the peer list and closes its link, if it has
+// one. Returns false if the peer wasn't in the peer list.
+func (c *Core) _removePeer(peer Peer) bool {
+	state, ok := c.config._peers[peer]
+	if !ok {
+		return false
+	}
+	if state.timer != nil {
+		state.timer.Stop()
+	}
+	if linkInfo := state.info; linkInfo != nil {
+		c.links.Act(nil, func() {
+			if link := c.links._links[*linkInfo]; link != nil {
+				_ = link.close()
+			}
+		})
+	}
+	delete(c.config._peers, peer)
+	return true
+}
+
+// _peerFor finds the peer list entry that a dialled link was created for, if
+// there is one. Links are matched by the URL pointer that was passed to
+// links.call, so links created with CallPeer never match.
+func (c *Core) _peerFor(u *url.URL) (Peer, *peerState, bool) {
+	for peer, state := range c.config._peers {
+		if state.url == u {
+			return peer, state, true
+		}
+	}
+	return Peer{}, nil, false
+}
+
+// _peerConnected is called when a dialled link completes the handshake.
+func (c *Core) _peerConnected(u *url.URL) {
+	_, state, ok := c._peerFor(u)
+	if !ok {
+		return
+	}
+	state.connected = true
+	state.failures = 0
+	state.next = time.Time{}
+	if state.timer != nil {
+		state.timer.Stop()
+		state.timer = nil
+	}
+}
+
+// _peerDisconnected is called when a dialled link goes away, whether it failed
+// the handshake or was disconnected later on.
+func (c *Core) _peerDisconnected(u *url.URL) {
+	peer, state, ok := c._peerFor(u)
+	if !ok {
+		return
+	}
+	state.connected = false
+	c._peerFailed(peer, state)
+}
+
+// _peerFailed schedules the next attempt to connect to a peer, backing off
+// exponentially with each consecutive failure.
+func (c *Core) _peerFailed(peer Peer, state *peerState) {
+	if c.config._peers[peer] != state || c.ctx.Err() != nil {
+		return
+	}
+	state.failures++
+	c._schedulePeer(peer, state, c._peerBackoff(state.failures))
+}
+
+// _peerPending is called when a call was started without error. Normally the
+// link will report back once the handshake has finished, but if it doesn't,
+// e.g. because an existing link was found, then make sure that we will try
+// again eventually.
+func (c *Core) _peerPending(peer Peer, state *peerState) {
+	if c.config._peers[peer] != state || state.connected || state.timer != nil {
+		return
+	}
+	c._schedulePeer(peer, state, c.config.peerRetryMax)
+}
+
+func (c *Core) _schedulePeer(peer Peer, state *peerState, delay time.Duration) {
+	if state.timer != nil {
+		state.timer.Stop()
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(delay, func() {
+		c.Act(nil, func() {
+			// Ignore the timer if it has been replaced or the peer removed.
+			if state.timer != timer || c.config._peers[peer] != state {
+				return
+			}
+			state.timer = nil
+			state.next = time.Time{}
+			go c.retryPeer(peer, state)
+		})
+	})
+	state.timer = timer
+	state.next = time.Now().Add(delay)
+}
+
+// _peerBackoff returns the delay before the next attempt after the given
+// number of consecutive failures. The delay doubles with each failure, up
+// to the maximum interval, and is then randomised to between half and all
+// of that so that nodes which lost a peer at the same time, e.g. because
+// it restarted, don't all try to reconnect to it at the same time.
+func (c *Core) _peerBackoff(failures uint) time.Duration {
+	delay := c.config.peerRetryMin
+	for i := uint(1); i < failures && delay < c.config.peerRetryMax; i++ {
+		delay *= 2
+	}
+	if delay > c.config.peerRetryMax {
+		delay = c.config.peerRetryMax
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// peerSource is an entry in the peer list that stands for a set of peers that
+// is looked up from somewhere else, e.g. DNS, instead of being a peer itself.
+// The lookup is repeated every refresh interval, and the peers that it found
+// are added to or removed from the peer list to match. It is owned by the
+// Core actor.
+type peerSource struct {
+	lookup  func(ctx context.Context) ([]string, error)
+	refresh time.Duration
+	peers   map[Peer]struct{} // peers in the peer list that came from this source
+	timer   *time.Timer
+}
+
+// The longest that a peer source lookup may take.
+const peerSourceTimeout = time.Second * 30
+
+// peerSourceFor returns a peer source for the given peer list entry, or nil if
+// the entry is an ordinary peer.
+func (c *Core) peerSourceFor(u *url.URL) (*peerSource, error) {
+	switch u.Scheme {
+	case "dns-srv":
+		return c.newDNSPeerSource(u)
+	default:
+		return nil, nil
+	}
+}
+
+// _addPeerSource adds a peer source and starts looking up its peers.
+func (c *Core) _addPeerSource(peer Peer, source *peerSource) {
+	source.peers = make(map[Peer]struct{})
+	c.config._peerSources[peer] = source
+	go c.refreshPeerSource(peer, source)
+}
+
+// _removePeerSource removes a peer source along with the peers that it added.
+func (c *Core) _removePeerSource(peer Peer) bool {
+	source, ok := c.config._peerSources[peer]
+	if !ok {
+		return false
+	}
+	if source.timer != nil {
+		source.timer.Stop()
+	}
+	for p := range source.peers {
+		c._removePeer(p)
+	}
+	delete(c.config._peerSources, peer)
+	return true
+}
+
+// refreshPeerSource looks up the peers from a source and updates the peer list
+// to match, then schedules the next lookup. This must not be called from the
+// Core actor, as the lookup can take a while.
+func (c *Core) refreshPeerSource(peer Peer, source *peerSource) {
+	ctx, cancel := context.WithTimeout(c.ctx, peerSourceTimeout)
+	defer cancel()
+	uris, err := source.lookup(ctx)
+	phony.Block(c, func() {
+		if c.config._peerSources[peer] != source || c.ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			// Keep the peers that we already know about, as it's better to
+			// carry on using them than to drop them because of a blip.
+			c.log.Warnf("Failed to look up peers from %s: %s", peer.URI, err)
+		} else {
+			c._updatePeerSource(peer, source, uris)
+		}
+		if source.timer != nil {
+			source.timer.Stop()
+		}
+		source.timer = time.AfterFunc(source.refresh, func() {
+			c.refreshPeerSource(peer, source)
+		})
+	})
+}
+
+// _updatePeerSource adds and removes peers so that the peers from a source
+// match the given URIs. Peers that are already in the peer list, e.g. because
+// they were configured by hand, are left alone.
+func (c *Core) _updatePeerSource(peer Peer, source *peerSource, uris []string) {
+	found := make(map[Peer]struct{}, len(uris))
+	for _, uri := range uris {
+		found[Peer{URI: uri, SourceInterface: peer.SourceInterface}] = struct{}{}
+	}
+	for p := range source.peers {
+		if _, ok := found[p]; !ok {
+			c.log.Infof("Removing peer %s, no longer found in %s", p.URI, peer.URI)
+			c._removePeer(p)
+			delete(source.peers, p)
+		}
+	}
+	for p := range found {
+		if _, ok := source.peers[p]; ok {
+			continue
+		}
+		if _, ok := c.config._peers[p]; ok {
+			continue
+		}
+		u, err := url.Parse(p.URI)
+		if err != nil {
+			c.log.Warnf("Ignoring invalid peer %q from %s: %s", p.URI, peer.URI, err)
+			continue
+		}
+		c.log.Infof("Adding peer %s from %s", p.URI, peer.URI)
+		state := &peerState{url: u}
+		c.config._peers[p] = state
+		source.peers[p] = struct{}{}
+		go c.retryPeer(p, state)
+	}
+}
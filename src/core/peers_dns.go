@@ -0,0 +1,108 @@
+package core
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The default time between DNS lookups for a dns-srv peer source, used if it
+// is not set with ?refresh=X in the URI.
+const defaultDNSPeerRefresh = time.Minute * 5
+
+// The prefix of the TXT records on an SRV target that give the public key of
+// the node there, which is pinned when connecting to it.
+const dnsPeerKeyPrefix = "yggdrasil-key="
+
+// peerResolver is the part of net.Resolver that is used to look up peers,
+// so that it can be replaced in tests.
+type peerResolver interface {
+	LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+// newDNSPeerSource returns a peer source for a URI of the form
+// dns-srv://_yggdrasil._tcp.example.org, which looks up the SRV records for
+// that name and turns each of them into a peer, e.g. tls://host:port. Any TXT
+// records on an SRV target that start with "yggdrasil-key=" give the public key
+// of the node there, which is pinned. The following options can be set in the
+// query string:
+//
+//	scheme=tcp  the scheme of the peer URIs, "tls" if not set
+//	refresh=X   the number of seconds between lookups
+//
+// Any other options, e.g. password or priority, are copied to every peer.
+func (c *Core) newDNSPeerSource(u *url.URL) (*peerSource, error) {
+	if u.Host == "" {
+		return nil, fmt.Errorf("no name to look up in %q", u.Redacted())
+	}
+	query := u.Query()
+	scheme := "tls"
+	if s := query.Get("scheme"); s != "" {
+		scheme = s
+	}
+	refresh := defaultDNSPeerRefresh
+	if r := query.Get("refresh"); r != "" {
+		seconds, err := strconv.ParseUint(r, 10, 32)
+		if err != nil || seconds == 0 {
+			return nil, fmt.Errorf("refresh invalid: %q", r)
+		}
+		refresh = time.Duration(seconds) * time.Second
+	}
+	query.Del("scheme")
+	query.Del("refresh")
+	name := u.Host
+	return &peerSource{
+		refresh: refresh,
+		lookup: func(ctx context.Context) ([]string, error) {
+			return c.lookupDNSPeers(ctx, name, scheme, query)
+		},
+	}, nil
+}
+
+// lookupDNSPeers looks up the SRV records for the given name and returns a
+// peer URI for each of them.
+func (c *Core) lookupDNSPeers(ctx context.Context, name, scheme string, query url.Values) ([]string, error) {
+	_, srvs, err := c.resolver.LookupSRV(ctx, "", "", name)
+	if err != nil {
+		return nil, err
+	}
+	uris := make([]string, 0, len(srvs))
+	for _, srv := range srvs {
+		target := strings.TrimSuffix(srv.Target, ".")
+		if target == "" {
+			continue // a target of "." means that the service isn't available
+		}
+		peerQuery := url.Values{}
+		for k, v := range query {
+			peerQuery[k] = append([]string(nil), v...)
+		}
+		// The keys are optional, so a failed TXT lookup just means that there
+		// are none, rather than that the SRV record should be skipped.
+		if txts, err := c.resolver.LookupTXT(ctx, target); err == nil {
+			for _, txt := range txts {
+				if !strings.HasPrefix(txt, dnsPeerKeyPrefix) {
+					continue
+				}
+				key := strings.TrimPrefix(txt, dnsPeerKeyPrefix)
+				if _, err := hex.DecodeString(key); err != nil {
+					c.log.Warnf("Ignoring invalid key %q in TXT record for %s", key, target)
+					continue
+				}
+				peerQuery.Add("key", key)
+			}
+		}
+		peer := url.URL{
+			Scheme:   scheme,
+			Host:     net.JoinHostPort(target, strconv.Itoa(int(srv.Port))),
+			RawQuery: peerQuery.Encode(),
+		}
+		uris = append(uris, peer.String())
+	}
+	return uris, nil
+}
@@ -0,0 +1,171 @@
+package core
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Arceliar/phony"
+)
+
+// stubResolver answers DNS lookups from maps instead of the network.
+type stubResolver struct {
+	sync.Mutex
+	srv map[string][]*net.SRV
+	txt map[string][]string
+}
+
+func (r *stubResolver) LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	r.Lock()
+	defer r.Unlock()
+	srvs, ok := r.srv[name]
+	if !ok {
+		return "", nil, fmt.Errorf("no such host %s", name)
+	}
+	return name, srvs, nil
+}
+
+func (r *stubResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	r.Lock()
+	defer r.Unlock()
+	txts, ok := r.txt[name]
+	if !ok {
+		return nil, fmt.Errorf("no such host %s", name)
+	}
+	return txts, nil
+}
+
+func (r *stubResolver) set(name string, srvs []*net.SRV, txt map[string][]string) {
+	r.Lock()
+	defer r.Unlock()
+	r.srv[name] = srvs
+	r.txt = txt
+}
+
+func configuredPeerURIs(c *Core) []string {
+	var uris []string
+	for _, peer := range c.GetConfiguredPeers() {
+		uris = append(uris, peer.URI)
+	}
+	sort.Strings(uris)
+	return uris
+}
+
+func waitForPeerURIs(t *testing.T, c *Core, expected []string) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second * 5)
+	for {
+		uris := configuredPeerURIs(c)
+		if fmt.Sprint(uris) == fmt.Sprint(expected) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected peers %v, got %v", expected, uris)
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+}
+
+func TestDNSPeerSource(t *testing.T) {
+	_, sk, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := New(sk, GetLoggerWithPrefix("", false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Stop()
+
+	pub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := hex.EncodeToString(pub)
+	const name = "_yggdrasil._tcp.example.invalid"
+	resolver := &stubResolver{
+		srv: map[string][]*net.SRV{},
+		txt: map[string][]string{},
+	}
+	resolver.set(name, []*net.SRV{
+		{Target: "hub1.example.invalid.", Port: 443},
+		{Target: "hub2.example.invalid.", Port: 8443},
+	}, map[string][]string{
+		"hub1.example.invalid": {"v=spf1 -all", dnsPeerKeyPrefix + key},
+	})
+	c.resolver = resolver
+
+	source := "dns-srv://" + name + "?refresh=3600&priority=2"
+	if err = c.AddPeer(source, ""); err != nil {
+		t.Fatal(err)
+	}
+	waitForPeerURIs(t, c, []string{
+		"tls://hub1.example.invalid:443?key=" + key + "&priority=2",
+		"tls://hub2.example.invalid:8443?priority=2",
+	})
+	for _, peer := range c.GetConfiguredPeers() {
+		if peer.Source != source {
+			t.Fatalf("peer %s has source %q, expected %q", peer.URI, peer.Source, source)
+		}
+	}
+
+	// Move hub2 to a new port and look the records up again, as the timer
+	// would.
+	resolver.set(name, []*net.SRV{
+		{Target: "hub1.example.invalid.", Port: 443},
+		{Target: "hub2.example.invalid.", Port: 9443},
+	}, map[string][]string{
+		"hub1.example.invalid": {dnsPeerKeyPrefix + key},
+	})
+	var ps *peerSource
+	phony.Block(c, func() {
+		ps = c.config._peerSources[Peer{URI: source}]
+	})
+	c.refreshPeerSource(Peer{URI: source}, ps)
+	waitForPeerURIs(t, c, []string{
+		"tls://hub1.example.invalid:443?key=" + key + "&priority=2",
+		"tls://hub2.example.invalid:9443?priority=2",
+	})
+
+	// A failed lookup should leave the peers alone.
+	resolver.Lock()
+	delete(resolver.srv, name)
+	resolver.Unlock()
+	c.refreshPeerSource(Peer{URI: source}, ps)
+	if uris := configuredPeerURIs(c); len(uris) != 2 {
+		t.Fatalf("expected peers to be kept after a failed lookup, got %v", uris)
+	}
+
+	// Removing the source removes its peers.
+	if err = c.RemovePeer(source, ""); err != nil {
+		t.Fatal(err)
+	}
+	waitForPeerURIs(t, c, nil)
+}
+
+func TestDNSPeerSource_Invalid(t *testing.T) {
+	_, sk, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := New(sk, GetLoggerWithPrefix("", false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Stop()
+	for _, uri := range []string{
+		"dns-srv://",
+		"dns-srv://_yggdrasil._tcp.example.invalid?refresh=0",
+		"dns-srv://_yggdrasil._tcp.example.invalid?refresh=soon",
+	} {
+		if err := c.AddPeer(uri, ""); err == nil {
+			t.Fatalf("expected an error adding %q", uri)
+		}
+	}
+}
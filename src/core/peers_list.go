@@ -0,0 +1,209 @@
+package core
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// The default time between fetches of a peer list, used if it is not set in
+// the PeerList option.
+const defaultPeerListRefresh = time.Hour
+
+// The largest peer list document that will be accepted.
+const peerListMaxSize = 1 << 20
+
+// signedPeerList is the document that is served at a peer list URL. The
+// signature is over the exact bytes of the list as they appear in the
+// document, so that it doesn't depend on how the JSON is formatted.
+type signedPeerList struct {
+	List      json.RawMessage `json:"list"`      // a peerListDocument
+	Signature string          `json:"signature"` // hex-encoded ed25519 signature
+}
+
+type peerListDocument struct {
+	Peers  []string `json:"peers"`
+	Issued int64    `json:"issued"` // Unix time that the list was signed
+	TTL    uint64   `json:"ttl"`    // seconds after Issued that the list is valid for, 0 if forever
+}
+
+// expired returns true if the list is past its TTL, after which its peers are
+// no longer used.
+func (d *peerListDocument) expired(now time.Time) bool {
+	if d.TTL == 0 {
+		return false
+	}
+	return now.After(time.Unix(d.Issued, 0).Add(time.Duration(d.TTL) * time.Second))
+}
+
+// parsePeerList checks the signature on a peer list document and returns the
+// list that it contains.
+func parsePeerList(data []byte, key ed25519.PublicKey) (*peerListDocument, error) {
+	var signed signedPeerList
+	if err := json.Unmarshal(data, &signed); err != nil {
+		return nil, fmt.Errorf("invalid peer list: %w", err)
+	}
+	sig, err := hex.DecodeString(signed.Signature)
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return nil, errors.New("invalid peer list signature")
+	}
+	if !ed25519.Verify(key, signed.List, sig) {
+		return nil, errors.New("peer list signature doesn't match the publisher key")
+	}
+	var doc peerListDocument
+	if err := json.Unmarshal(signed.List, &doc); err != nil {
+		return nil, fmt.Errorf("invalid peer list: %w", err)
+	}
+	return &doc, nil
+}
+
+// peerList follows a signed list of peers that is published at a URL. The last
+// good copy is kept, and written to the cache file if there is one, so that
+// the peers can still be used if the URL can't be reached, until the list
+// expires.
+type peerList struct {
+	core    *Core
+	options PeerList
+	mutex   sync.Mutex
+	current *peerListDocument // last good list, nil if there isn't one yet
+	loaded  bool              // whether the cache file has been read
+}
+
+// newPeerListSource returns a peer source that fetches the given peer list.
+func (c *Core) newPeerListSource(options PeerList) (*peerSource, error) {
+	u, err := url.Parse(options.URL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("peer list URL %q must be http or https", options.URL)
+	}
+	if len(options.PublicKey) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("peer list %q needs a publisher key", options.URL)
+	}
+	refresh := options.Refresh
+	if refresh <= 0 {
+		refresh = defaultPeerListRefresh
+	}
+	l := &peerList{core: c, options: options}
+	return &peerSource{
+		refresh: refresh,
+		lookup:  l.lookup,
+	}, nil
+}
+
+// lookup fetches the peer list and returns the peers in it, falling back to the
+// last good copy if the fetch fails. An expired list has no peers, so that the
+// peers from it are removed.
+func (l *peerList) lookup(ctx context.Context) ([]string, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if !l.loaded {
+		l.loaded = true
+		l.loadCache()
+	}
+	data, doc, err := l.fetch(ctx)
+	switch {
+	case err != nil:
+	case l.current != nil && doc.Issued < l.current.Issued:
+		// Otherwise an old copy of the list could be served to undo changes.
+		err = fmt.Errorf("peer list was issued before the one that is already known")
+	default:
+		l.current = doc
+		l.saveCache(data)
+	}
+	if l.current == nil {
+		return nil, err
+	}
+	if err != nil {
+		l.core.log.Warnf("Failed to fetch peer list %s, using the last good copy: %s", l.options.URL, err)
+	}
+	if l.current.expired(time.Now()) {
+		l.core.log.Warnf("Peer list %s has expired", l.options.URL)
+		return []string{}, nil
+	}
+	return l.current.Peers, nil
+}
+
+func (l *peerList) fetch(ctx context.Context) ([]byte, *peerListDocument, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, l.options.URL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, peerListMaxSize+1))
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(data) > peerListMaxSize {
+		return nil, nil, fmt.Errorf("peer list is larger than %d bytes", peerListMaxSize)
+	}
+	doc, err := parsePeerList(data, l.options.PublicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, doc, nil
+}
+
+// loadCache reads the last good copy of the list from the cache file. The
+// signature is checked again, in case the file has been changed.
+func (l *peerList) loadCache() {
+	if l.options.CacheFile == "" {
+		return
+	}
+	data, err := os.ReadFile(l.options.CacheFile)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return
+	case err != nil:
+		l.core.log.Warnf("Failed to read peer list cache %s: %s", l.options.CacheFile, err)
+		return
+	}
+	doc, err := parsePeerList(data, l.options.PublicKey)
+	if err != nil {
+		l.core.log.Warnf("Ignoring peer list cache %s: %s", l.options.CacheFile, err)
+		return
+	}
+	l.current = doc
+}
+
+// saveCache writes the list to the cache file, by way of a temporary file so
+// that a partly written file is never left behind.
+func (l *peerList) saveCache(data []byte) {
+	if l.options.CacheFile == "" {
+		return
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(l.options.CacheFile), ".peerlist-*")
+	if err == nil {
+		_, err = tmp.Write(data)
+		if cerr := tmp.Close(); err == nil {
+			err = cerr
+		}
+		if err == nil {
+			err = os.Rename(tmp.Name(), l.options.CacheFile)
+		}
+		if err != nil {
+			_ = os.Remove(tmp.Name())
+		}
+	}
+	if err != nil {
+		l.core.log.Warnf("Failed to write peer list cache %s: %s", l.options.CacheFile, err)
+	}
+}
@@ -0,0 +1,183 @@
+package core
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Arceliar/phony"
+)
+
+// peerListServer serves a signed peer list, which can be changed or made
+// unavailable during a test.
+type peerListServer struct {
+	sync.Mutex
+	key  ed25519.PrivateKey
+	data []byte // nil to fail requests
+}
+
+func (s *peerListServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+	if s.data == nil {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	_, _ = w.Write(s.data)
+}
+
+func (s *peerListServer) publish(t *testing.T, doc peerListDocument) {
+	t.Helper()
+	list, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(signedPeerList{
+		List:      list,
+		Signature: hex.EncodeToString(ed25519.Sign(s.key, list)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Lock()
+	defer s.Unlock()
+	s.data = data
+}
+
+func (s *peerListServer) fail() {
+	s.Lock()
+	defer s.Unlock()
+	s.data = nil
+}
+
+func refreshPeerList(c *Core, uri string) {
+	var ps *peerSource
+	phony.Block(c, func() {
+		ps = c.config._peerSources[Peer{URI: uri}]
+	})
+	c.refreshPeerSource(Peer{URI: uri}, ps)
+}
+
+func TestPeerList(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &peerListServer{key: priv}
+	now := time.Now().Unix()
+	server.publish(t, peerListDocument{
+		Peers:  []string{"tls://192.0.2.1:443", "tcp://192.0.2.2:1234"},
+		Issued: now,
+		TTL:    3600,
+	})
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	_, sk, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache := filepath.Join(t.TempDir(), "peers.json")
+	list := PeerList{
+		URL:       ts.URL,
+		PublicKey: pub,
+		Refresh:   time.Hour,
+		CacheFile: cache,
+	}
+	c, err := New(sk, GetLoggerWithPrefix("", false), list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForPeerURIs(t, c, []string{"tcp://192.0.2.2:1234", "tls://192.0.2.1:443"})
+	for _, peer := range c.GetConfiguredPeers() {
+		if peer.Source != ts.URL {
+			t.Fatalf("peer %s has source %q, expected %q", peer.URI, peer.Source, ts.URL)
+		}
+	}
+
+	// Peers that are no longer listed are removed.
+	server.publish(t, peerListDocument{
+		Peers:  []string{"tls://192.0.2.1:443", "tls://192.0.2.3:443"},
+		Issued: now + 1,
+		TTL:    3600,
+	})
+	refreshPeerList(c, ts.URL)
+	waitForPeerURIs(t, c, []string{"tls://192.0.2.1:443", "tls://192.0.2.3:443"})
+
+	// An older list, or one with a bad signature, is refused.
+	server.publish(t, peerListDocument{
+		Peers:  []string{"tls://192.0.2.1:443"},
+		Issued: now,
+		TTL:    3600,
+	})
+	refreshPeerList(c, ts.URL)
+	_, other, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.key = other
+	server.publish(t, peerListDocument{
+		Peers:  []string{"tls://192.0.2.4:443"},
+		Issued: now + 2,
+	})
+	refreshPeerList(c, ts.URL)
+	waitForPeerURIs(t, c, []string{"tls://192.0.2.1:443", "tls://192.0.2.3:443"})
+	c.Stop()
+
+	// A new node uses the cached copy when the list can't be fetched.
+	server.fail()
+	c, err = New(sk, GetLoggerWithPrefix("", false), list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Stop()
+	waitForPeerURIs(t, c, []string{"tls://192.0.2.1:443", "tls://192.0.2.3:443"})
+
+	// Removing the list removes its peers.
+	if err = c.RemovePeerList(ts.URL); err != nil {
+		t.Fatal(err)
+	}
+	waitForPeerURIs(t, c, nil)
+}
+
+func TestPeerList_Expired(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &peerListServer{key: priv}
+	server.publish(t, peerListDocument{
+		Peers:  []string{"tls://192.0.2.1:443"},
+		Issued: time.Now().Add(-time.Hour * 2).Unix(),
+		TTL:    3600,
+	})
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	_, sk, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := New(sk, GetLoggerWithPrefix("", false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Stop()
+	if err = c.AddPeerList(PeerList{URL: ts.URL, PublicKey: pub}); err != nil {
+		t.Fatal(err)
+	}
+	refreshPeerList(c, ts.URL)
+	waitForPeerURIs(t, c, nil)
+	if err = c.AddPeerList(PeerList{URL: "ftp://example.invalid", PublicKey: pub}); err == nil {
+		t.Fatal("expected an error adding a peer list with an unsupported scheme")
+	}
+	if err = c.AddPeerList(PeerList{URL: ts.URL + "/other"}); err == nil {
+		t.Fatal("expected an error adding a peer list without a key")
+	}
+}
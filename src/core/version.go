@@ -4,65 +4,249 @@ package core
 // Used in the initial connection setup and key exchange
 // Some of this could arguably go in wire.go instead
 
-import "crypto/ed25519"
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
 
-// This is the version-specific metadata exchanged at the start of a connection.
-// It must always begin with the 4 bytes "meta" and a wire formatted uint64 major version number.
-// The current version also includes a minor version number, and the box/sig/link keys that need to be exchanged to open a connection.
+// This is the metadata exchanged at the start of a connection. On the wire, it
+// always begins with the 4 bytes "meta" and a big-endian uint16 giving the
+// length of the rest, which is a sequence of TLV fields: a uint16 type, a
+// uint16 length and then that many bytes of value. Fields of unknown types are
+// ignored, so that new fields can be added without breaking older nodes.
 type version_metadata struct {
-	meta [4]byte
-	ver  uint8 // 1 byte in this version
-	// Everything after this point potentially depends on the version number, and is subject to change in future versions
-	minorVer uint8 // 1 byte in this version
-	key      ed25519.PublicKey
+	meta       [4]byte
+	minVersion version_number // lowest protocol version supported
+	maxVersion version_number // highest protocol version supported
+	key        ed25519.PublicKey
+	nonce      [32]byte // random for each connection, so that signatures can't be replayed
+	features   uint64   // bitmask of optional features that are supported
+	priority   uint8
+	name       string
+}
+
+// Metadata field types. These must never be renumbered or reused.
+const (
+	metaVersionRange = iota + 1 // 4 bytes: min major, min minor, max major, max minor
+	metaPublicKey               // ed25519 public key
+	metaNonce                   // 32 random bytes
+	metaFeatures                // big-endian uint64 bitmask
+	metaPriority                // 1 byte link priority
+	metaName                    // UTF-8 node name, for information only
+)
+
+// Metadata feature flags, which are only used if both sides set them.
+const (
+	metaFeatureCompression uint64 = 1 << iota // compress the link, see linkCompression
+)
+
+// The length of the "meta" prefix and the length of the fields that follow.
+const version_headerLength = 6
+
+// The longest node name that we will send in the metadata.
+const version_maxNameLength = 64
+
+type version_number struct {
+	major uint8
+	minor uint8
+}
+
+func (v version_number) less(o version_number) bool {
+	return v.major < o.major || (v.major == o.major && v.minor < o.minor)
+}
+
+func (v version_number) String() string {
+	return fmt.Sprintf("%d.%d", v.major, v.minor)
 }
 
 // Gets a base metadata with no keys set, but with the correct version numbers.
+// Nodes negotiate the highest version that they both support, so raising the
+// maximum version doesn't cut off nodes that only support older versions, so
+// long as the minimum version stays the same.
 func version_getBaseMetadata() version_metadata {
 	return version_metadata{
-		meta:     [4]byte{'m', 'e', 't', 'a'},
-		ver:      0,
-		minorVer: 4,
+		meta:       [4]byte{'m', 'e', 't', 'a'},
+		minVersion: version_number{0, 8},
+		maxVersion: version_number{0, 8},
 	}
 }
 
-// Gets the length of the metadata for this version, used to know how many bytes to read from the start of a connection.
-func version_getMetaLength() (mlen int) {
-	mlen += 4                     // meta
-	mlen++                        // ver, as long as it's < 127, which it is in this version
-	mlen++                        // minorVer, as long as it's < 127, which it is in this version
-	mlen += ed25519.PublicKeySize // key
-	return
-}
-
 // Encodes version metadata into its wire format.
 func (m *version_metadata) encode() []byte {
-	bs := make([]byte, 0, version_getMetaLength())
-	bs = append(bs, m.meta[:]...)
-	bs = append(bs, m.ver)
-	bs = append(bs, m.minorVer)
-	bs = append(bs, m.key[:]...)
-	if len(bs) != version_getMetaLength() {
-		panic("Inconsistent metadata length")
+	bs := make([]byte, version_headerLength, 128)
+	copy(bs, m.meta[:])
+	field := func(t uint16, v []byte) {
+		bs = binary.BigEndian.AppendUint16(bs, t)
+		bs = binary.BigEndian.AppendUint16(bs, uint16(len(v)))
+		bs = append(bs, v...)
 	}
+	field(metaVersionRange, []byte{
+		m.minVersion.major, m.minVersion.minor,
+		m.maxVersion.major, m.maxVersion.minor,
+	})
+	field(metaPublicKey, m.key)
+	field(metaNonce, m.nonce[:])
+	field(metaFeatures, binary.BigEndian.AppendUint64(nil, m.features))
+	field(metaPriority, []byte{m.priority})
+	if name := m.name; name != "" {
+		if len(name) > version_maxNameLength {
+			name = name[:version_maxNameLength]
+		}
+		field(metaName, []byte(name))
+	}
+	binary.BigEndian.PutUint16(bs[4:version_headerLength], uint16(len(bs)-version_headerLength))
 	return bs
 }
 
-// Decodes version metadata from its wire format into the struct.
+// Decodes the header of the version metadata, returning the length of the
+// fields that follow it.
+func (m *version_metadata) decodeHeader(bs []byte) (int, bool) {
+	if len(bs) < version_headerLength {
+		return 0, false
+	}
+	copy(m.meta[:], bs)
+	if m.meta != version_getBaseMetadata().meta {
+		return 0, false
+	}
+	return int(binary.BigEndian.Uint16(bs[4:version_headerLength])), true
+}
+
+// Decodes version metadata from its wire format into the struct. Fields of
+// unknown types are skipped, but the version range, key and nonce must all be
+// present.
 func (m *version_metadata) decode(bs []byte) bool {
-	if len(bs) != version_getMetaLength() {
+	length, ok := m.decodeHeader(bs)
+	if !ok || len(bs) != version_headerLength+length {
 		return false
 	}
-	offset := 0
-	offset += copy(m.meta[:], bs[offset:])
-	m.ver, offset = bs[offset], offset+1
-	m.minorVer, offset = bs[offset], offset+1
-	m.key = append([]byte(nil), bs[offset:]...)
-	return true
+	var seenVersion, seenKey, seenNonce bool
+	for bs = bs[version_headerLength:]; len(bs) > 0; {
+		if len(bs) < 4 {
+			return false
+		}
+		t := binary.BigEndian.Uint16(bs[0:2])
+		l := int(binary.BigEndian.Uint16(bs[2:4]))
+		if len(bs) < 4+l {
+			return false
+		}
+		v := bs[4 : 4+l]
+		bs = bs[4+l:]
+		switch t {
+		case metaVersionRange:
+			if l != 4 {
+				return false
+			}
+			m.minVersion = version_number{v[0], v[1]}
+			m.maxVersion = version_number{v[2], v[3]}
+			seenVersion = true
+		case metaPublicKey:
+			if l != ed25519.PublicKeySize {
+				return false
+			}
+			m.key = append(ed25519.PublicKey(nil), v...)
+			seenKey = true
+		case metaNonce:
+			if l != len(m.nonce) {
+				return false
+			}
+			copy(m.nonce[:], v)
+			seenNonce = true
+		case metaFeatures:
+			if l != 8 {
+				return false
+			}
+			m.features = binary.BigEndian.Uint64(v)
+		case metaPriority:
+			if l != 1 {
+				return false
+			}
+			m.priority = v[0]
+		case metaName:
+			m.name = string(v)
+		}
+	}
+	return seenVersion && seenKey && seenNonce
+}
+
+// Finds the highest protocol version that is supported by both sides. Returns
+// false if the supported version ranges don't overlap.
+func (m *version_metadata) negotiate(remote *version_metadata) (version_number, bool) {
+	v := m.maxVersion
+	if remote.maxVersion.less(v) {
+		v = remote.maxVersion
+	}
+	if v.less(m.minVersion) || v.less(remote.minVersion) {
+		return v, false
+	}
+	return v, true
+}
+
+// The length of the authentication message that each side sends once the
+// metadata has been exchanged, which contains a password proof followed by a
+// signature.
+const version_authLength = sha512.Size + ed25519.SignatureSize
+
+var (
+	errPasswordMismatch = errors.New("password does not match")
+	errBadSignature     = errors.New("handshake signature is not valid")
+	errReflected        = errors.New("handshake was reflected back to us")
+)
+
+// Gets the handshake transcript that the sender authenticates, which is made
+// up of the encoded metadata from both sides, the sender's first. Since both
+// sides include a fresh nonce in their metadata, the transcript is different
+// for every connection, and putting the sender first means that a message
+// can't be reflected back to the side that sent it.
+func version_transcript(sender, receiver []byte) []byte {
+	transcript := make([]byte, 0, 19+len(sender)+len(receiver))
+	transcript = append(transcript, "yggdrasil handshake"...)
+	transcript = append(transcript, sender...)
+	transcript = append(transcript, receiver...)
+	return transcript
+}
+
+// Gets the authentication message that we send to the remote side, given the
+// encoded metadata that was sent in each direction. It consists of a keyed MAC
+// over the transcript, which shows that we know the password without revealing
+// it, and a signature over the transcript, which shows that we own the private
+// key for the public key in our metadata.
+//
+// This is sent on every link, even if no password is set, as the signature is
+// what proves ownership of the key. Nodes that don't send it can't complete the
+// handshake.
+func version_auth(secret ed25519.PrivateKey, password, local, remote []byte) []byte {
+	transcript := version_transcript(local, remote)
+	mac := hmac.New(sha512.New, password)
+	_, _ = mac.Write(transcript)
+	auth := make([]byte, 0, version_authLength)
+	auth = mac.Sum(auth)
+	return append(auth, ed25519.Sign(secret, transcript)...)
 }
 
-// Checks that the "meta" bytes and the version numbers are the expected values.
-func (m *version_metadata) check() bool {
-	base := version_getBaseMetadata()
-	return base.meta == m.meta && base.ver == m.ver && base.minorVer == m.minorVer
+// Checks the authentication message received from the remote side, given the
+// remote public key and the encoded metadata that was sent in each direction.
+func version_checkAuth(auth []byte, key ed25519.PublicKey, password, remote, local []byte) error {
+	if len(auth) != version_authLength || len(key) != ed25519.PublicKeySize {
+		return errBadSignature
+	}
+	// If the remote side sent back exactly what we sent, then the transcript
+	// reads the same in both directions, so our own messages would pass.
+	if bytes.Equal(remote, local) {
+		return errReflected
+	}
+	transcript := version_transcript(remote, local)
+	if !ed25519.Verify(key, transcript, auth[sha512.Size:]) {
+		return errBadSignature
+	}
+	mac := hmac.New(sha512.New, password)
+	_, _ = mac.Write(transcript)
+	if !hmac.Equal(auth[:sha512.Size], mac.Sum(nil)) {
+		return errPasswordMismatch
+	}
+	return nil
 }
@@ -0,0 +1,185 @@
+package core
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+func testMetadata(t *testing.T) version_metadata {
+	pub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta := version_getBaseMetadata()
+	meta.key = pub
+	meta.nonce[0] = 1
+	meta.features = 3
+	meta.priority = 4
+	meta.name = "test"
+	return meta
+}
+
+func TestVersion_Metadata_Decode(t *testing.T) {
+	meta := testMetadata(t)
+	var decoded version_metadata
+	if !decoded.decode(meta.encode()) {
+		t.Fatal("failed to decode metadata")
+	}
+	if !bytes.Equal(decoded.key, meta.key) || decoded.nonce != meta.nonce ||
+		decoded.minVersion != meta.minVersion || decoded.maxVersion != meta.maxVersion ||
+		decoded.features != meta.features || decoded.priority != meta.priority ||
+		decoded.name != meta.name {
+		t.Fatalf("decoded metadata %+v does not match %+v", decoded, meta)
+	}
+}
+
+func TestVersion_Metadata_UnknownFields(t *testing.T) {
+	meta := testMetadata(t)
+	bs := meta.encode()
+	bs = binary.BigEndian.AppendUint16(bs, 0xfff0)
+	bs = binary.BigEndian.AppendUint16(bs, 3)
+	bs = append(bs, 1, 2, 3)
+	binary.BigEndian.PutUint16(bs[4:], uint16(len(bs)-version_headerLength))
+	var decoded version_metadata
+	if !decoded.decode(bs) {
+		t.Fatal("failed to decode metadata with an unknown field")
+	}
+	if !bytes.Equal(decoded.key, meta.key) {
+		t.Fatal("decoded key does not match")
+	}
+}
+
+func TestVersion_Metadata_Truncated(t *testing.T) {
+	meta := testMetadata(t)
+	bs := meta.encode()
+	var decoded version_metadata
+	if decoded.decode(bs[:len(bs)-1]) {
+		t.Fatal("decoded truncated metadata")
+	}
+	bs = bs[:version_headerLength]
+	binary.BigEndian.PutUint16(bs[4:], 0)
+	if decoded.decode(bs) {
+		t.Fatal("decoded metadata with missing fields")
+	}
+}
+
+func TestVersion_Metadata_Negotiate(t *testing.T) {
+	local := version_getBaseMetadata()
+	local.minVersion = version_number{0, 8}
+	local.maxVersion = version_number{0, 10}
+	for _, test := range []struct {
+		min, max version_number
+		expected version_number
+		ok       bool
+	}{
+		// The ranges overlap, so the highest common version is used.
+		{version_number{0, 8}, version_number{0, 8}, version_number{0, 8}, true},
+		{version_number{0, 9}, version_number{0, 12}, version_number{0, 10}, true},
+		{version_number{0, 4}, version_number{0, 9}, version_number{0, 9}, true},
+		{version_number{0, 10}, version_number{1, 0}, version_number{0, 10}, true},
+		// The ranges don't overlap.
+		{version_number{0, 4}, version_number{0, 7}, version_number{0, 7}, false},
+		{version_number{0, 11}, version_number{1, 0}, version_number{0, 10}, false},
+	} {
+		meta := testMetadata(t)
+		meta.minVersion, meta.maxVersion = test.min, test.max
+		var remote version_metadata
+		if !remote.decode(meta.encode()) {
+			t.Fatal("failed to decode metadata")
+		}
+		for _, sides := range [][2]*version_metadata{{&local, &remote}, {&remote, &local}} {
+			version, ok := sides[0].negotiate(sides[1])
+			if ok != test.ok || (ok && version != test.expected) {
+				t.Fatalf("negotiating with %s-%s gave %s (%v), expected %s (%v)",
+					test.min, test.max, version, ok, test.expected, test.ok)
+			}
+		}
+	}
+}
+
+// testAuthSide sets up one side of a handshake, returning its key and its
+// encoded metadata with a fresh nonce.
+func testAuthSide(t *testing.T) (ed25519.PrivateKey, []byte) {
+	pk, sk, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta := version_getBaseMetadata()
+	meta.key = pk
+	if _, err = rand.Read(meta.nonce[:]); err != nil {
+		t.Fatal(err)
+	}
+	return sk, meta.encode()
+}
+
+func TestVersion_Auth(t *testing.T) {
+	skA, metaA := testAuthSide(t)
+	skB, metaB := testAuthSide(t)
+	pkA := skA.Public().(ed25519.PublicKey)
+	password := []byte("password")
+
+	auth := version_auth(skA, password, metaA, metaB)
+	if err := version_checkAuth(auth, pkA, password, metaA, metaB); err != nil {
+		t.Fatalf("valid auth was rejected: %v", err)
+	}
+	if err := version_checkAuth(version_auth(skA, nil, metaA, metaB), pkA, nil, metaA, metaB); err != nil {
+		t.Fatalf("valid auth with no password was rejected: %v", err)
+	}
+
+	t.Run("BadSignature", func(t *testing.T) {
+		bad := append([]byte(nil), auth...)
+		bad[len(bad)-1] ^= 1
+		if err := version_checkAuth(bad, pkA, password, metaA, metaB); !errors.Is(err, errBadSignature) {
+			t.Fatalf("expected a bad signature, got %v", err)
+		}
+		// Signed by a key other than the one in the metadata.
+		forged := version_auth(skB, password, metaA, metaB)
+		if err := version_checkAuth(forged, pkA, password, metaA, metaB); !errors.Is(err, errBadSignature) {
+			t.Fatalf("expected a bad signature, got %v", err)
+		}
+		if err := version_checkAuth(auth[:len(auth)-1], pkA, password, metaA, metaB); !errors.Is(err, errBadSignature) {
+			t.Fatalf("expected a bad signature for truncated auth, got %v", err)
+		}
+	})
+
+	t.Run("WrongPassword", func(t *testing.T) {
+		if err := version_checkAuth(auth, pkA, []byte("wrong"), metaA, metaB); !errors.Is(err, errPasswordMismatch) {
+			t.Fatalf("expected a password mismatch, got %v", err)
+		}
+	})
+
+	t.Run("PasswordOnOneSide", func(t *testing.T) {
+		if err := version_checkAuth(auth, pkA, nil, metaA, metaB); !errors.Is(err, errPasswordMismatch) {
+			t.Fatalf("expected a password mismatch, got %v", err)
+		}
+		noPassword := version_auth(skA, nil, metaA, metaB)
+		if err := version_checkAuth(noPassword, pkA, password, metaA, metaB); !errors.Is(err, errPasswordMismatch) {
+			t.Fatalf("expected a password mismatch, got %v", err)
+		}
+	})
+
+	t.Run("Reflected", func(t *testing.T) {
+		// B's own message, sent back to B as if it came from A.
+		authB := version_auth(skB, password, metaB, metaA)
+		if err := version_checkAuth(authB, pkA, password, metaA, metaB); !errors.Is(err, errBadSignature) {
+			t.Fatalf("expected a bad signature, got %v", err)
+		}
+		// A's metadata and auth echoed straight back to A.
+		if err := version_checkAuth(auth, pkA, password, metaA, metaA); !errors.Is(err, errReflected) {
+			t.Fatalf("expected a reflected handshake, got %v", err)
+		}
+	})
+
+	t.Run("Replayed", func(t *testing.T) {
+		// The same message from A, replayed on a later connection where B
+		// has sent a fresh nonce.
+		_, laterB := testAuthSide(t)
+		if err := version_checkAuth(auth, pkA, password, metaA, laterB); !errors.Is(err, errBadSignature) {
+			t.Fatalf("expected a bad signature, got %v", err)
+		}
+	})
+}
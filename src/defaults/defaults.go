@@ -48,9 +48,19 @@ func GenerateConfig() *config.NodeConfig {
 	cfg.NewKeys()
 	cfg.Listen = []string{}
 	cfg.AdminListen = defaults.DefaultAdminListen
+	cfg.AdminTokens = []config.AdminTokenConfig{}
 	cfg.Peers = []string{}
 	cfg.InterfacePeers = map[string][]string{}
+	cfg.PeerLists = []config.PeerListConfig{}
+	cfg.PeerRetryMinInterval = 1
+	cfg.PeerRetryMaxInterval = 60
+	cfg.HeartbeatInterval = 15
+	cfg.HeartbeatTimeout = 60
 	cfg.AllowedPublicKeys = []string{}
+	cfg.BlockedPublicKeys = []string{}
+	cfg.BlockedAddresses = []string{}
+	cfg.AutoBanThreshold = 0
+	cfg.AutoBanDuration = 600
 	cfg.MulticastInterfaces = defaults.DefaultMulticastInterfaces
 	cfg.IfName = defaults.DefaultIfName
 	cfg.IfMTU = defaults.DefaultIfMTU
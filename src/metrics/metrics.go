@@ -0,0 +1,197 @@
+package metrics
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yggdrasil-network/yggdrasil-go/src/address"
+	"github.com/yggdrasil-network/yggdrasil-go/src/core"
+	"github.com/yggdrasil-network/yggdrasil-go/src/version"
+)
+
+// Metrics serves statistics about the node for Prometheus, or anything else
+// that reads the same text format, at /metrics. Other modules add their own
+// statistics with AddCollector, in the same way as they add admin handlers.
+type Metrics struct {
+	core       *core.Core
+	log        core.Logger
+	server     *http.Server
+	mutex      sync.Mutex // protects collectors
+	collectors []Collector
+	config     struct {
+		listenaddr     ListenAddress
+		sessionMetrics bool
+	}
+}
+
+// A Collector writes the current values of some metrics. It is called every
+// time that the metrics are scraped.
+type Collector func(w *Writer)
+
+// New starts serving metrics on the configured listen address, or returns nil
+// if there isn't one.
+func New(c *core.Core, log core.Logger, opts ...SetupOption) (*Metrics, error) {
+	m := &Metrics{
+		core: c,
+		log:  log,
+	}
+	for _, opt := range opts {
+		m._applyOption(opt)
+	}
+	if m.config.listenaddr == "none" || m.config.listenaddr == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(string(m.config.listenaddr))
+	if err != nil {
+		return nil, fmt.Errorf("invalid metrics listen address: %w", err)
+	}
+	if !strings.EqualFold(u.Scheme, "http") {
+		return nil, fmt.Errorf("metrics listen address %q must be http", m.config.listenaddr)
+	}
+	listener, err := net.Listen("tcp", u.Host)
+	if err != nil {
+		return nil, fmt.Errorf("metrics failed to listen: %w", err)
+	}
+	m.AddCollector(m.collectCore)
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m)
+	m.server = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: time.Second * 10,
+	}
+	m.log.Infof("Metrics listening on http://%s/metrics", listener.Addr())
+	go func() {
+		if err := m.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			m.log.Errorf("Metrics server failed: %s", err)
+		}
+	}()
+	return m, nil
+}
+
+// AddCollector adds a function that writes metrics when they are scraped.
+func (m *Metrics) AddCollector(collector Collector) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.collectors = append(m.collectors, collector)
+}
+
+// Stop stops serving metrics.
+func (m *Metrics) Stop() error {
+	if m == nil || m.server == nil {
+		return nil
+	}
+	return m.server.Close()
+}
+
+// ServeHTTP writes the metrics from all of the collectors.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	m.mutex.Lock()
+	collectors := append([]Collector(nil), m.collectors...)
+	m.mutex.Unlock()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	writer := newWriter(w)
+	for _, collector := range collectors {
+		collector(writer)
+	}
+	if err := writer.flush(); err != nil {
+		m.log.Debugln("Metrics write error:", err)
+	}
+}
+
+// collectCore writes the statistics from the core: the node itself, its peers,
+// sessions, DHT and paths, and the handshakes that have been rejected.
+func (m *Metrics) collectCore(w *Writer) {
+	w.Describe("yggdrasil_build_info", Gauge, "Build name and version of the node, always 1.")
+	w.Sample("yggdrasil_build_info", 1, "name", version.BuildName(), "version", version.BuildVersion())
+
+	self := m.core.GetSelf()
+	w.Describe("yggdrasil_self_info", Gauge, "Public key and address of the node, and the root of its tree, always 1.")
+	w.Sample("yggdrasil_self_info", 1,
+		"key", hex.EncodeToString(m.core.PublicKey()),
+		"address", net.IP(address.AddrForKey(m.core.PublicKey())[:]).String(),
+		"root", hex.EncodeToString(self.Root),
+	)
+	w.Describe("yggdrasil_tree_depth", Gauge, "Distance of the node from the root of the spanning tree.")
+	w.Sample("yggdrasil_tree_depth", float64(len(self.Coords)))
+
+	peers := m.core.GetPeers()
+	sort.Slice(peers, func(i, j int) bool {
+		return peers[i].Port < peers[j].Port
+	})
+	w.Describe("yggdrasil_peers", Gauge, "Number of connected peers.")
+	w.Sample("yggdrasil_peers", float64(len(peers)))
+	w.Describe("yggdrasil_peer_received_bytes_total", Counter, "Bytes received from a peer since it connected.")
+	w.Describe("yggdrasil_peer_sent_bytes_total", Counter, "Bytes sent to a peer since it connected.")
+	w.Describe("yggdrasil_peer_uptime_seconds", Gauge, "Time since a peer connected.")
+	w.Describe("yggdrasil_peer_port", Gauge, "Port number of a peer in the spanning tree.")
+	w.Describe("yggdrasil_peer_priority", Gauge, "Priority of the link to a peer.")
+	w.Describe("yggdrasil_peer_rtt_seconds", Gauge, "Round trip time to a peer, measured by heartbeats.")
+	for _, p := range peers {
+		labels := []string{"key", hex.EncodeToString(p.Key), "remote", p.Remote}
+		w.Sample("yggdrasil_peer_received_bytes_total", float64(p.RXBytes), labels...)
+		w.Sample("yggdrasil_peer_sent_bytes_total", float64(p.TXBytes), labels...)
+		w.Sample("yggdrasil_peer_uptime_seconds", p.Uptime.Seconds(), labels...)
+		w.Sample("yggdrasil_peer_port", float64(p.Port), labels...)
+		w.Sample("yggdrasil_peer_priority", float64(p.Priority), labels...)
+		w.Sample("yggdrasil_peer_rtt_seconds", p.RTT.Seconds(), labels...)
+	}
+
+	// Sessions come and go with every node that we exchange traffic with, so
+	// a series for each one can add up to a lot of series over time. Only the
+	// totals are exported unless SessionMetrics is set.
+	sessions := m.core.GetSessions()
+	var rx, tx uint64
+	for _, s := range sessions {
+		rx += s.RXBytes
+		tx += s.TXBytes
+	}
+	w.Describe("yggdrasil_sessions", Gauge, "Number of established traffic sessions.")
+	w.Sample("yggdrasil_sessions", float64(len(sessions)))
+	w.Describe("yggdrasil_sessions_received_bytes", Gauge, "Bytes received in the sessions that are currently established.")
+	w.Sample("yggdrasil_sessions_received_bytes", float64(rx))
+	w.Describe("yggdrasil_sessions_sent_bytes", Gauge, "Bytes sent in the sessions that are currently established.")
+	w.Sample("yggdrasil_sessions_sent_bytes", float64(tx))
+	if m.config.sessionMetrics {
+		sort.Slice(sessions, func(i, j int) bool {
+			return hex.EncodeToString(sessions[i].Key) < hex.EncodeToString(sessions[j].Key)
+		})
+		w.Describe("yggdrasil_session_received_bytes_total", Counter, "Bytes received in a session since it was established.")
+		w.Describe("yggdrasil_session_sent_bytes_total", Counter, "Bytes sent in a session since it was established.")
+		w.Describe("yggdrasil_session_uptime_seconds", Gauge, "Time since a session was established.")
+		for _, s := range sessions {
+			key := hex.EncodeToString(s.Key)
+			w.Sample("yggdrasil_session_received_bytes_total", float64(s.RXBytes), "key", key)
+			w.Sample("yggdrasil_session_sent_bytes_total", float64(s.TXBytes), "key", key)
+			w.Sample("yggdrasil_session_uptime_seconds", s.Uptime.Seconds(), "key", key)
+		}
+	}
+
+	w.Describe("yggdrasil_dht_entries", Gauge, "Number of known DHT entries.")
+	w.Sample("yggdrasil_dht_entries", float64(len(m.core.GetDHT())))
+	w.Describe("yggdrasil_paths", Gauge, "Number of established paths through the node.")
+	w.Sample("yggdrasil_paths", float64(len(m.core.GetPaths())))
+
+	failures := m.core.GetHandshakeFailures()
+	reasons := make([]string, 0, len(failures))
+	for reason := range failures {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+	w.Describe("yggdrasil_handshake_failures_total", Counter, "Link handshakes that were rejected, by reason.")
+	for _, reason := range reasons {
+		w.Sample("yggdrasil_handshake_failures_total", float64(failures[reason]), "reason", reason)
+	}
+}
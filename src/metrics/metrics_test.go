@@ -0,0 +1,85 @@
+package metrics
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gologme/log"
+
+	"github.com/yggdrasil-network/yggdrasil-go/src/core"
+)
+
+func TestMetrics_ServeHTTP(t *testing.T) {
+	m := &Metrics{log: log.New(io.Discard, "", 0)}
+	m.AddCollector(func(w *Writer) {
+		w.Describe("test_bytes_total", Counter, "Bytes sent\nto a peer.")
+		w.Sample("test_bytes_total", 1234, "key", "abcd", "remote", `tls://"a\b"`)
+		w.Sample("test_bytes_total", 1.5e9, "key", "ef01", "remote", "tcp://c:d")
+	})
+	m.AddCollector(func(w *Writer) {
+		w.Describe("test_bytes_total", Counter, "Described twice.")
+		w.Describe("test_peers", Gauge, "Number of peers.")
+		w.Sample("test_peers", 2)
+	})
+	server := httptest.NewServer(m)
+	defer server.Close()
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `# HELP test_bytes_total Bytes sent\nto a peer.
+# TYPE test_bytes_total counter
+test_bytes_total{key="abcd",remote="tls://\"a\\b\""} 1234
+test_bytes_total{key="ef01",remote="tcp://c:d"} 1.5e+09
+# HELP test_peers Number of peers.
+# TYPE test_peers gauge
+test_peers 2
+`
+	if string(body) != expected {
+		t.Fatalf("unexpected metrics:\n%s\nexpected:\n%s", body, expected)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/plain; version=0.0.4; charset=utf-8" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+}
+
+// TestMetrics_SessionMetrics checks that session totals are always exported,
+// but the series for each session only when asked for.
+func TestMetrics_SessionMetrics(t *testing.T) {
+	_, sk, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := core.New(sk, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Stop()
+	for _, enabled := range []bool{false, true} {
+		m := &Metrics{core: c, log: log.New(io.Discard, "", 0)}
+		m._applyOption(SessionMetrics(enabled))
+		var buf bytes.Buffer
+		w := newWriter(&buf)
+		m.collectCore(w)
+		if err := w.flush(); err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range []string{"yggdrasil_sessions", "yggdrasil_sessions_received_bytes", "yggdrasil_sessions_sent_bytes"} {
+			if !bytes.Contains(buf.Bytes(), []byte("\n"+name+" 0\n")) {
+				t.Fatalf("%s is missing with SessionMetrics(%v):\n%s", name, enabled, buf.String())
+			}
+		}
+		if exported := bytes.Contains(buf.Bytes(), []byte("# TYPE yggdrasil_session_received_bytes_total ")); exported != enabled {
+			t.Fatalf("per-session metrics exported: %v, with SessionMetrics(%v)", exported, enabled)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package metrics
+
+func (m *Metrics) _applyOption(opt SetupOption) {
+	switch v := opt.(type) {
+	case ListenAddress:
+		m.config.listenaddr = v
+	case SessionMetrics:
+		m.config.sessionMetrics = bool(v)
+	}
+}
+
+type SetupOption interface {
+	isSetupOption()
+}
+
+type ListenAddress string // e.g. http://[::1]:9003
+type SessionMetrics bool  // export metrics for each session, not just totals
+
+func (a ListenAddress) isSetupOption()  {}
+func (a SessionMetrics) isSetupOption() {}
@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// The types of metric that can be given to Writer.Describe.
+const (
+	Counter = "counter"
+	Gauge   = "gauge"
+)
+
+// Writer writes metrics in the Prometheus text exposition format. Each metric
+// should be described once, before its samples are written.
+type Writer struct {
+	w         *bufio.Writer
+	described map[string]struct{}
+}
+
+func newWriter(w io.Writer) *Writer {
+	return &Writer{
+		w:         bufio.NewWriter(w),
+		described: make(map[string]struct{}),
+	}
+}
+
+// Describe writes the help text and type for a metric, unless it has already
+// been described.
+func (w *Writer) Describe(name, kind, help string) {
+	if _, ok := w.described[name]; ok {
+		return
+	}
+	w.described[name] = struct{}{}
+	_, _ = w.w.WriteString("# HELP " + name + " " + helpEscaper.Replace(help) + "\n")
+	_, _ = w.w.WriteString("# TYPE " + name + " " + kind + "\n")
+}
+
+// Sample writes a sample for a metric. The labels are given as pairs of names
+// and values, e.g. "key", "abcd", "remote", "tls://a.b.c.d:e".
+func (w *Writer) Sample(name string, value float64, labels ...string) {
+	_, _ = w.w.WriteString(name)
+	if len(labels) > 0 {
+		_ = w.w.WriteByte('{')
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				_ = w.w.WriteByte(',')
+			}
+			_, _ = w.w.WriteString(labels[i] + `="` + labelEscaper.Replace(labels[i+1]) + `"`)
+		}
+		_ = w.w.WriteByte('}')
+	}
+	_, _ = w.w.WriteString(" " + strconv.FormatFloat(value, 'g', -1, 64) + "\n")
+}
+
+func (w *Writer) flush() error {
+	return w.w.Flush()
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
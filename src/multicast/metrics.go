@@ -0,0 +1,54 @@
+package multicast
+
+import (
+	"sort"
+
+	"github.com/Arceliar/phony"
+
+	"github.com/yggdrasil-network/yggdrasil-go/src/metrics"
+)
+
+// BeaconStats counts the multicast beacons that have been sent and received on
+// an interface.
+type BeaconStats struct {
+	Sent     uint64
+	Received uint64 // valid beacons from other nodes, whether or not they were called
+}
+
+func (m *Multicast) _beaconStats(intf string) *BeaconStats {
+	stats := m._beacons[intf]
+	if stats == nil {
+		stats = &BeaconStats{}
+		m._beacons[intf] = stats
+	}
+	return stats
+}
+
+// GetBeaconStats returns the number of beacons that have been sent and received
+// on each interface since startup.
+func (m *Multicast) GetBeaconStats() map[string]BeaconStats {
+	stats := make(map[string]BeaconStats)
+	phony.Block(m, func() {
+		for intf, s := range m._beacons {
+			stats[intf] = *s
+		}
+	})
+	return stats
+}
+
+func (m *Multicast) SetupMetrics(mm *metrics.Metrics) {
+	mm.AddCollector(func(w *metrics.Writer) {
+		stats := m.GetBeaconStats()
+		interfaces := make([]string, 0, len(stats))
+		for intf := range stats {
+			interfaces = append(interfaces, intf)
+		}
+		sort.Strings(interfaces)
+		w.Describe("yggdrasil_multicast_beacons_sent_total", metrics.Counter, "Multicast beacons sent on an interface.")
+		w.Describe("yggdrasil_multicast_beacons_received_total", metrics.Counter, "Multicast beacons received from other nodes on an interface.")
+		for _, intf := range interfaces {
+			w.Sample("yggdrasil_multicast_beacons_sent_total", float64(stats[intf].Sent), "interface", intf)
+			w.Sample("yggdrasil_multicast_beacons_received_total", float64(stats[intf].Received), "interface", intf)
+		}
+	})
+}
@@ -31,6 +31,8 @@ type Multicast struct {
 	_listeners  map[string]*listenerInfo
 	_interfaces map[string]*interfaceInfo
 	_timer      *time.Timer
+	_beacons    map[string]*BeaconStats // by interface name, since startup
+	_discovered map[string]time.Time    // by key and interface, when last seen
 	config      struct {
 		_groupAddr  GroupAddress
 		_interfaces map[MulticastInterface]struct{}
@@ -62,6 +64,8 @@ func New(core *core.Core, log *log.Logger, opts ...SetupOption) (*Multicast, err
 		log:         log,
 		_listeners:  make(map[string]*listenerInfo),
 		_interfaces: make(map[string]*interfaceInfo),
+		_beacons:    make(map[string]*BeaconStats),
+		_discovered: make(map[string]time.Time),
 	}
 	m.config._interfaces = map[MulticastInterface]struct{}{}
 	m.config._groupAddr = GroupAddress("[ff02::114]:9001")
@@ -141,6 +145,37 @@ func (m *Multicast) _stop() error {
 	return nil
 }
 
+// SetInterfaces replaces the multicast interface configuration. Any existing
+// multicast listeners are stopped, so that they are started again with the new
+// settings, and the module is started or stopped as needed.
+func (m *Multicast) SetInterfaces(interfaces ...MulticastInterface) error {
+	var err error
+	phony.Block(m, func() {
+		m.config._interfaces = make(map[MulticastInterface]struct{}, len(interfaces))
+		var anyEnabled bool
+		for _, intf := range interfaces {
+			m.config._interfaces[intf] = struct{}{}
+			anyEnabled = anyEnabled || intf.Beacon || intf.Listen
+		}
+		for name, info := range m._listeners {
+			_ = info.listener.Close()
+			delete(m._listeners, name)
+		}
+		switch {
+		case anyEnabled && !m._isOpen:
+			err = m._start()
+		case !anyEnabled && m._isOpen:
+			err = m._stop()
+		case anyEnabled:
+			if m._timer != nil {
+				m._timer.Stop()
+			}
+			m.Act(nil, m._announce)
+		}
+	})
+	return err
+}
+
 func (m *Multicast) _updateInterfaces() {
 	interfaces := m._getAllowedInterfaces()
 	for name, info := range interfaces {
@@ -331,7 +366,9 @@ func (m *Multicast) _announce() {
 				pbs := make([]byte, 2)
 				binary.BigEndian.PutUint16(pbs, uint16(a.Port))
 				msg = append(msg, pbs...)
-				_, _ = m.sock.WriteTo(msg, nil, destAddr)
+				if _, err := m.sock.WriteTo(msg, nil, destAddr); err == nil {
+					m._beaconStats(iface.Name).Sent++
+				}
 			}
 			if linfo.interval.Seconds() < 15 {
 				linfo.interval += time.Second
@@ -396,6 +433,8 @@ func (m *Multicast) listen() {
 		var interfaces map[string]*interfaceInfo
 		phony.Block(m, func() {
 			interfaces = m._interfaces
+			m._beaconStats(from.Zone).Received++
+			m._discover(key, from)
 		})
 		if info, ok := interfaces[from.Zone]; ok && info.listen {
 			addr.Zone = ""
@@ -410,3 +449,33 @@ func (m *Multicast) listen() {
 		}
 	}
 }
+
+// The time without beacons from a node after which it is forgotten, so that it
+// will be reported as discovered again if it comes back.
+const discoveredExpiry = time.Minute
+
+// _discover publishes an event when a beacon is received from a node that
+// hasn't been seen recently on the same interface.
+func (m *Multicast) _discover(key ed25519.PublicKey, from *net.UDPAddr) {
+	now := time.Now()
+	for k, seen := range m._discovered {
+		if now.Sub(seen) > discoveredExpiry {
+			delete(m._discovered, k)
+		}
+	}
+	k := hex.EncodeToString(key) + "%" + from.Zone
+	_, known := m._discovered[k]
+	m._discovered[k] = now
+	if known {
+		return
+	}
+	m.core.PublishEvent(core.Event{
+		Type: core.EventMulticastDiscovered,
+		Time: now,
+		Key:  key,
+		Fields: map[string]string{
+			"remote":    from.IP.String(),
+			"interface": from.Zone,
+		},
+	})
+}
@@ -1,5 +1,7 @@
 package tun
 
+import "sync/atomic"
+
 const TUN_OFFSET_BYTES = 4
 
 func (tun *TunAdapter) read() {
@@ -17,7 +19,10 @@ func (tun *TunAdapter) read() {
 		begin := TUN_OFFSET_BYTES
 		end := begin + n
 		bs := buf[begin:end]
+		atomic.AddUint64(&tun.stats.ReadPackets, 1)
+		atomic.AddUint64(&tun.stats.ReadBytes, uint64(len(bs)))
 		if _, err := tun.rwc.Write(bs); err != nil {
+			atomic.AddUint64(&tun.stats.ReadDropped, 1)
 			tun.log.Debugln("Unable to send packet:", err)
 		}
 	}
@@ -36,7 +41,11 @@ func (tun *TunAdapter) write() {
 			continue // Nothing to do, the tun isn't enabled
 		}
 		bs = buf[:TUN_OFFSET_BYTES+n]
-		if _, err = tun.iface.Write(bs, TUN_OFFSET_BYTES); err != nil {
+		if _, err = tun.iface.Write(bs, TUN_OFFSET_BYTES); err == nil {
+			atomic.AddUint64(&tun.stats.WrittenPackets, 1)
+			atomic.AddUint64(&tun.stats.WrittenBytes, uint64(n))
+		} else {
+			atomic.AddUint64(&tun.stats.WriteDropped, 1)
 			tun.Act(nil, func() {
 				if !tun.isOpen {
 					tun.log.Errorln("TUN iface write error:", err)
@@ -0,0 +1,20 @@
+package tun
+
+import (
+	"github.com/yggdrasil-network/yggdrasil-go/src/metrics"
+)
+
+func (t *TunAdapter) SetupMetrics(m *metrics.Metrics) {
+	m.AddCollector(func(w *metrics.Writer) {
+		stats := t.GetStats()
+		w.Describe("yggdrasil_tun_packets_total", metrics.Counter, "Packets read from or written to the TUN interface.")
+		w.Sample("yggdrasil_tun_packets_total", float64(stats.ReadPackets), "direction", "read")
+		w.Sample("yggdrasil_tun_packets_total", float64(stats.WrittenPackets), "direction", "write")
+		w.Describe("yggdrasil_tun_bytes_total", metrics.Counter, "Bytes read from or written to the TUN interface.")
+		w.Sample("yggdrasil_tun_bytes_total", float64(stats.ReadBytes), "direction", "read")
+		w.Sample("yggdrasil_tun_bytes_total", float64(stats.WrittenBytes), "direction", "write")
+		w.Describe("yggdrasil_tun_dropped_packets_total", metrics.Counter, "Packets that couldn't be sent into the network or written to the TUN interface.")
+		w.Sample("yggdrasil_tun_dropped_packets_total", float64(stats.ReadDropped), "direction", "read")
+		w.Sample("yggdrasil_tun_dropped_packets_total", float64(stats.WriteDropped), "direction", "write")
+	})
+}
@@ -9,6 +9,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"sync/atomic"
 
 	"github.com/Arceliar/phony"
 	"golang.zx2c4.com/wireguard/tun"
@@ -26,6 +27,7 @@ type MTU uint16
 // should pass this object to the yggdrasil.SetRouterAdapter() function before
 // calling yggdrasil.Start().
 type TunAdapter struct {
+	stats       Stats // accessed atomically, so first to keep it 64-bit aligned on 32-bit platforms
 	rwc         *ipv6rwc.ReadWriteCloser
 	log         core.Logger
 	addr        address.Address
@@ -42,6 +44,31 @@ type TunAdapter struct {
 	}
 }
 
+// Stats counts the packets that have passed through the TUN adapter since
+// startup. Read packets are those that were read from the TUN interface to be
+// sent into the network, and written packets are those that were received from
+// the network and written to the TUN interface.
+type Stats struct {
+	ReadPackets    uint64
+	ReadBytes      uint64
+	ReadDropped    uint64 // packets that couldn't be sent into the network
+	WrittenPackets uint64
+	WrittenBytes   uint64
+	WriteDropped   uint64 // packets that couldn't be written to the TUN interface
+}
+
+// GetStats returns the packet counters for the TUN adapter.
+func (tun *TunAdapter) GetStats() Stats {
+	return Stats{
+		ReadPackets:    atomic.LoadUint64(&tun.stats.ReadPackets),
+		ReadBytes:      atomic.LoadUint64(&tun.stats.ReadBytes),
+		ReadDropped:    atomic.LoadUint64(&tun.stats.ReadDropped),
+		WrittenPackets: atomic.LoadUint64(&tun.stats.WrittenPackets),
+		WrittenBytes:   atomic.LoadUint64(&tun.stats.WrittenBytes),
+		WriteDropped:   atomic.LoadUint64(&tun.stats.WriteDropped),
+	}
+}
+
 // Gets the maximum supported MTU for the platform based on the defaults in
 // defaults.GetDefaults().
 func getSupportedMTU(mtu uint64) uint64 {